@@ -24,12 +24,18 @@ func NewTag(configId string, scraperId string) *Tag {
 	}
 }
 
-// GetConfigId implements Taggable.GetConfigId.
+// GetConfigId implements Taggable.GetConfigId, an empty string is returned if it has not been tagged yet.
 func (t *Tag) GetConfigId() string {
+	if t == nil {
+		return ""
+	}
 	return t.configId
 }
 
-// GetScraperId implements Taggable.GetScraperId.
+// GetScraperId implements Taggable.GetScraperId, an empty string is returned if it has not been tagged yet.
 func (t *Tag) GetScraperId() string {
+	if t == nil {
+		return ""
+	}
 	return t.scraperId
 }
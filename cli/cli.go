@@ -0,0 +1,259 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/mmaaskant/gro-crop-scraper/config"
+	"github.com/mmaaskant/gro-crop-scraper/database"
+	"github.com/mmaaskant/gro-crop-scraper/logger"
+	"io"
+	"os"
+	"strings"
+)
+
+// Command is a subcommand of the Cli, e.g. "run" or "validate".
+type Command struct {
+	Name        string
+	Usage       string
+	Description string
+	run         func(c *Cli, cmd *Command, args []string) error
+}
+
+// Cli parses the arguments the scraper was invoked with and runs the matching Command,
+// output is written to out and errors and usage to errOut.
+type Cli struct {
+	out       io.Writer
+	errOut    io.Writer
+	args      []string
+	configDir string
+	commands  []*Command
+}
+
+func NewCli(out io.Writer, errOut io.Writer) *Cli {
+	return &Cli{
+		out,
+		errOut,
+		make([]string, 0),
+		"",
+		newCommands(),
+	}
+}
+
+// usageError is returned by a Command that was invoked with invalid arguments, which prints its usage.
+type usageError struct {
+	message string
+}
+
+func (e *usageError) Error() string {
+	return e.message
+}
+
+func usageErrorf(format string, a ...any) error {
+	return &usageError{fmt.Sprintf(format, a...)}
+}
+
+// newCommands returns all commands in the order they are listed in.
+func newCommands() []*Command {
+	return []*Command{
+		{"run", "run [--steps <steps>] [--refilter] [config_id[/scraper_id] ...]", "Runs the given steps of the selected configs or scrapers, all of them by default.", runRun},
+		{"crawl", "crawl [config_id[/scraper_id] ...]", "Runs the crawl step, which saves raw data from external sources so it can be processed.", runCrawl},
+		{"filter", "filter [--refilter] [config_id[/scraper_id] ...]", "Runs the filter step, which filters raw data and saves any data that is noteworthy.", runFilter},
+		{"map", "map [config_id[/scraper_id] ...]", "Runs the map step, which maps filtered data to a universal format.", runMap},
+		{"compile", "compile [config_id[/scraper_id] ...]", "Runs the compile step, which merges mapped data based on their values.", runCompile},
+		{"daemon", "daemon [--refilter] [config_id[/scraper_id] ...]", "Keeps running and runs the steps of the selected configs on their schedule.", runDaemon},
+		{"list-configs", "list-configs [config_id[/scraper_id] ...]", "Lists all configs, their scrapers, steps and schedules.", runListConfigs},
+		{"validate", "validate [file_or_dir ...]", "Validates declarative config files, the config directory by default.", runValidate},
+		{"runs", "runs list [limit] | runs show <run_id>", "Lists the most recent runs, or summarises a single run.", runRuns},
+		{"export", "export --config <config_id> [--scraper <scraper_id>] [--run <run_id>] [-o <file>]", "Exports scraped pages to a WARC file.", runExport},
+		{"import", "import [--config <config_id> --scraper <scraper_id>] <file>", "Imports scraped pages from a WARC file.", runImport},
+		{"deadletter", "deadletter list [config_id] [scraper_id] [step] | inspect <id> | requeue <config_id> <scraper_id> <step>", "Lists, inspects and requeues items that a step failed to process.", runDeadLetter},
+		{"playground", "playground <config_id> <scraper_id> <url_file_or_document_id>", "Runs a single scraper's filter against a source and traces every matched criteria.", runPlayground},
+		{"migrate", "migrate", "Moves raw bodies stored before compression was introduced to the raw_bodies table.", runMigrate},
+	}
+}
+
+// Run parses the given arguments, excluding the program name, runs the matching Command and returns the exit code.
+// Global flags precede the command, the "run" command is run if no command is given.
+func (c *Cli) Run(args []string) int {
+	c.args = args
+	fs := flag.NewFlagSet("gro", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	logLevel := fs.String("log-level", "", "Sets the log level to debug, info, warn or error, overrides the LOG_LEVEL env variable.")
+	fs.StringVar(&c.configDir, "config-dir", os.Getenv("SCRAPER_CONFIG_DIR"), "Directory holding declarative configs, overrides the SCRAPER_CONFIG_DIR env variable.")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			c.printUsage(fs)
+			return 0
+		}
+		fmt.Fprintf(c.errOut, "Error: %s\n\n", err)
+		c.printUsage(fs)
+		return 2
+	}
+	if err := logger.Configure(*logLevel, ""); err != nil {
+		fmt.Fprintf(c.errOut, "Error: invalid --log-level, %s\n", err)
+		return 2
+	}
+	name, cmdArgs := "run", fs.Args()
+	if len(cmdArgs) > 0 {
+		name, cmdArgs = cmdArgs[0], cmdArgs[1:]
+	}
+	if name == "help" {
+		if len(cmdArgs) > 0 && c.findCommand(cmdArgs[0]) != nil {
+			c.printCommandUsage(c.findCommand(cmdArgs[0]))
+		} else {
+			c.printUsage(fs)
+		}
+		return 0
+	}
+	cmd := c.findCommand(name)
+	if cmd == nil {
+		fmt.Fprintf(c.errOut, "Error: unknown command %s\n\n", name)
+		c.printUsage(fs)
+		return 2
+	}
+	err := cmd.run(c, cmd, cmdArgs)
+	var ue *usageError
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, flag.ErrHelp):
+		c.printCommandUsage(cmd)
+		return 0
+	case errors.As(err, &ue):
+		fmt.Fprintf(c.errOut, "Error: %s\n\n", err)
+		c.printCommandUsage(cmd)
+		return 2
+	default:
+		fmt.Fprintf(c.errOut, "Error: %s\n", err)
+		return 1
+	}
+}
+
+func (c *Cli) findCommand(name string) *Command {
+	for _, cmd := range c.commands {
+		if cmd.Name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+func (c *Cli) printUsage(fs *flag.FlagSet) {
+	fmt.Fprint(c.errOut, "Usage: gro [global flags] <command> [flags] [arguments]\n\nCommands:\n")
+	for _, cmd := range c.commands {
+		fmt.Fprintf(c.errOut, "  %-13s %s\n", cmd.Name, cmd.Description)
+	}
+	fmt.Fprint(c.errOut, "\nGlobal flags:\n")
+	fs.SetOutput(c.errOut)
+	fs.PrintDefaults()
+	fs.SetOutput(io.Discard)
+	fmt.Fprint(c.errOut, "\nUse \"gro help <command>\" for more information about a command.\n")
+}
+
+func (c *Cli) printCommandUsage(cmd *Command) {
+	fmt.Fprintf(c.errOut, "Usage: gro %s\n\n%s\n", cmd.Usage, cmd.Description)
+	fs := c.newFlagSet(cmd)
+	for _, f := range commandFlags[cmd.Name] {
+		f(fs)
+	}
+	if hasFlags(fs) {
+		fmt.Fprint(c.errOut, "\nFlags:\n")
+		fs.SetOutput(c.errOut)
+		fs.PrintDefaults()
+	}
+}
+
+// commandFlags registers the flags of each Command, so they can be both parsed and printed along with its usage.
+var commandFlags = map[string][]func(fs *flag.FlagSet){
+	"run":    {stepsFlag, refilterFlag},
+	"filter": {refilterFlag},
+	"daemon": {refilterFlag},
+	"export": {configFlag, scraperFlag, runFlag, outputFlag},
+	"import": {configFlag, scraperFlag},
+}
+
+// newFlagSet returns a flag.FlagSet for the given Command, which reports errors rather than exiting.
+func (c *Cli) newFlagSet(cmd *Command) *flag.FlagSet {
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return fs
+}
+
+// parseFlags registers and parses the flags of the given Command, and returns the remaining arguments.
+func (c *Cli) parseFlags(cmd *Command, args []string) (*flag.FlagSet, error) {
+	fs := c.newFlagSet(cmd)
+	for _, f := range commandFlags[cmd.Name] {
+		f(fs)
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil, err
+		}
+		return nil, usageErrorf("%s", err)
+	}
+	return fs, nil
+}
+
+func hasFlags(fs *flag.FlagSet) bool {
+	has := false
+	fs.VisitAll(func(*flag.Flag) {
+		has = true
+	})
+	return has
+}
+
+func stepsFlag(fs *flag.FlagSet) {
+	fs.String("steps", "", fmt.Sprintf("Comma separated steps to run, all by default, one of %s.", strings.Join(config.StepIds, ", ")))
+}
+
+func refilterFlag(fs *flag.FlagSet) {
+	fs.Bool("refilter", false, "Filters scraped data again if it was filtered by a previous version of its filter.")
+}
+
+func configFlag(fs *flag.FlagSet) {
+	fs.String("config", "", "ID of the config to export or import pages for.")
+}
+
+func scraperFlag(fs *flag.FlagSet) {
+	fs.String("scraper", "", "ID of the scraper to export or import pages for.")
+}
+
+func runFlag(fs *flag.FlagSet) {
+	fs.String("run", "", "ID of the run to export pages for, exports pages of all runs if empty.")
+}
+
+func outputFlag(fs *flag.FlagSet) {
+	fs.String("o", "", "File to export to, defaults to <config_id>.warc.gz.")
+}
+
+// getString returns the value of a string flag registered by commandFlags.
+func getString(fs *flag.FlagSet, name string) string {
+	return fs.Lookup(name).Value.String()
+}
+
+// getBool returns the value of a bool flag registered by commandFlags.
+func getBool(fs *flag.FlagSet, name string) bool {
+	return fs.Lookup(name).Value.(flag.Getter).Get().(bool)
+}
+
+// selectConfigs loads all configs and selects those matching the given selectors, see config.Select.
+func (c *Cli) selectConfigs(selectors []string) ([]*config.Config, error) {
+	configs, err := config.GetConfigs(c.configDir)
+	if err != nil {
+		return nil, err
+	}
+	selected, err := config.Select(configs, selectors)
+	if err != nil {
+		return nil, usageErrorf("%s", err)
+	}
+	return selected, nil
+}
+
+func connect() (*database.Db, error) {
+	db, err := database.NewDb(database.NewMongoDbDriver())
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database, error: %w", err)
+	}
+	return db, nil
+}
@@ -0,0 +1,35 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCli_Run(t *testing.T) {
+	for _, c := range []struct {
+		args     []string
+		code     int
+		contains string
+	}{
+		{[]string{"--config-dir", "../config/testdata/valid", "list-configs", "rest_example"}, 0, "rest_example/"},
+		{[]string{"list-configs", "burpee/burpee_html"}, 0, "burpee/burpee_html steps: crawl,filter"},
+		{[]string{"list-configs", "unknown"}, 2, "no config found with ID unknown"},
+		{[]string{"validate", "../config/testdata/valid"}, 0, "ok   ../config/testdata/valid: burpee, magento_example, magento_site_example, rest_example, shopify_example"},
+		{[]string{"validate", "../config/testdata/invalid/broken.yaml"}, 1, "FAIL ../config/testdata/invalid/broken.yaml"},
+		{[]string{"run", "--steps", "scrape"}, 2, "unknown step"},
+		{[]string{"map"}, 1, "the map step has not been implemented yet"},
+		{[]string{"export", "--unknown"}, 2, "Usage: gro export"},
+		{[]string{"help", "import"}, 0, "Usage: gro import"},
+		{[]string{"scrape"}, 2, "unknown command scrape"},
+	} {
+		out, errOut := &bytes.Buffer{}, &bytes.Buffer{}
+		code := NewCli(out, errOut).Run(c.args)
+		if code != c.code {
+			t.Errorf("Expected %v to exit with %d, got %d, output:\n%s%s", c.args, c.code, code, out, errOut)
+		}
+		if !strings.Contains(out.String()+errOut.String(), c.contains) {
+			t.Errorf("Expected output of %v to contain %q, got:\n%s%s", c.args, c.contains, out, errOut)
+		}
+	}
+}
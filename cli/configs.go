@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"fmt"
+	"github.com/mmaaskant/gro-crop-scraper/config"
+	"os"
+	"sort"
+	"strings"
+)
+
+// runListConfigs prints the selected configs along with their scrapers, the steps and workers of each scraper and
+// the schedule of each step.
+func runListConfigs(c *Cli, cmd *Command, args []string) error {
+	fs, err := c.parseFlags(cmd, args)
+	if err != nil {
+		return err
+	}
+	configs, err := c.selectConfigs(fs.Args())
+	if err != nil {
+		return err
+	}
+	for _, cfg := range configs {
+		fmt.Fprintln(c.out, cfg.Id)
+		steps := make([]string, 0, len(cfg.Schedules))
+		for step := range cfg.Schedules {
+			steps = append(steps, step)
+		}
+		sort.Strings(steps)
+		for _, step := range steps {
+			fmt.Fprintf(c.out, "  schedule %s: %s\n", step, cfg.Schedules[step])
+		}
+		for _, s := range cfg.Scrapers {
+			supported := make([]string, 0)
+			if s.Crawler != nil {
+				supported = append(supported, config.CrawlMethodStepId)
+			}
+			if s.Filter != nil {
+				supported = append(supported, config.FilterMethodStepId)
+			}
+			fmt.Fprintf(c.out, "  %s/%s steps: %s", cfg.Id, s.GetScraperId(), strings.Join(supported, ","))
+			if s.Concurrency != nil {
+				fmt.Fprintf(c.out, " workers: crawl=%d per_host=%d filter=%d",
+					s.Concurrency.CrawlWorkers, s.Concurrency.HostWorkers, s.Concurrency.FilterWorkers,
+				)
+			}
+			fmt.Fprintln(c.out)
+		}
+	}
+	return nil
+}
+
+// runValidate loads the given declarative config files and directories, or the config directory if none are given,
+// and prints every problem found. Directories are also checked for config IDs used by configs written in Go.
+func runValidate(c *Cli, cmd *Command, args []string) error {
+	fs, err := c.parseFlags(cmd, args)
+	if err != nil {
+		return err
+	}
+	paths := fs.Args()
+	if len(paths) == 0 {
+		if c.configDir == "" {
+			return usageErrorf("no files or directories given, and no config directory set")
+		}
+		paths = []string{c.configDir}
+	}
+	invalid := 0
+	for _, path := range paths {
+		configs, err := loadPath(path)
+		if err != nil {
+			invalid++
+			fmt.Fprintf(c.out, "FAIL %s\n%s\n", path, err)
+			continue
+		}
+		ids := make([]string, 0, len(configs))
+		for _, cfg := range configs {
+			ids = append(ids, cfg.Id)
+		}
+		fmt.Fprintf(c.out, "ok   %s: %s\n", path, strings.Join(ids, ", "))
+	}
+	if invalid > 0 {
+		return fmt.Errorf("%d of %d paths hold invalid configs", invalid, len(paths))
+	}
+	return nil
+}
+
+// loadPath loads all configs in the given directory, along with the configs written in Go, or the given file.
+func loadPath(path string) ([]*config.Config, error) {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return config.GetConfigs(path)
+	}
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	return []*config.Config{cfg}, nil
+}
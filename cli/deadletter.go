@@ -0,0 +1,217 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/mmaaskant/gro-crop-scraper/config"
+	"github.com/mmaaskant/gro-crop-scraper/crawler"
+	"github.com/mmaaskant/gro-crop-scraper/database"
+	"github.com/mmaaskant/gro-crop-scraper/filter"
+	"github.com/mmaaskant/gro-crop-scraper/logger"
+	"github.com/mmaaskant/gro-crop-scraper/scraper"
+	"go.mongodb.org/mongo-driver/mongo"
+	"os"
+	"strconv"
+)
+
+// runDeadLetter lists, inspects or requeues the items stored in the "dead_letters" table.
+func runDeadLetter(c *Cli, cmd *Command, args []string) error {
+	switch {
+	case len(args) > 0 && args[0] == "list" && len(args) <= 4:
+	case len(args) == 2 && args[0] == "inspect":
+	case len(args) == 4 && args[0] == "requeue":
+	default:
+		return usageErrorf("expected list, inspect or requeue")
+	}
+	db, err := connect()
+	if err != nil {
+		return err
+	}
+	switch args[0] {
+	case "list":
+		return c.listDeadLetters(db, args[1:])
+	case "inspect":
+		return c.inspectDeadLetter(db, args[1])
+	}
+	configs, err := c.selectConfigs([]string{args[1] + "/" + args[2]})
+	if err != nil {
+		return err
+	}
+	return c.requeueDeadLetters(db, configs, args[1], args[2], args[3])
+}
+
+// listDeadLetters prints a summary of all dead letters matching the given config ID, scraper ID and step.
+func (c *Cli) listDeadLetters(db *database.Db, args []string) error {
+	params := make(map[string]any)
+	for i, key := range []string{"config_id", "scraper_id", "step"} {
+		if i < len(args) {
+			params[key] = args[i]
+		}
+	}
+	iterator, err := db.GetMany(database.DeadLetterTableName, params)
+	if err != nil {
+		return fmt.Errorf("failed to fetch dead letters, error: %w", err)
+	}
+	for e, _ := iterator.Next(); e != nil; e, _ = iterator.Next() {
+		errorMessage := fmt.Sprint(e.Data["error"])
+		if len(errorMessage) > 120 {
+			errorMessage = errorMessage[:117] + "..."
+		}
+		fmt.Fprintf(c.out, "%v %s %s/%s attempts: %v status: %s\n  %s\n  %s\n",
+			e.Id, e.Data["step"], e.Data["config_id"], e.Data["scraper_id"], e.Data["attempts"], e.Data["status"],
+			e.Data["key"], errorMessage,
+		)
+	}
+	return nil
+}
+
+// inspectDeadLetter prints the dead letter matching the given ID as JSON.
+func (c *Cli) inspectDeadLetter(db *database.Db, deadLetterId string) error {
+	id, err := db.ParseId(deadLetterId)
+	if err != nil {
+		return fmt.Errorf("invalid dead letter ID %s, error: %w", deadLetterId, err)
+	}
+	e, err := db.GetOne(database.DeadLetterTableName, map[string]any{"_id": id})
+	if err != nil {
+		return fmt.Errorf("failed to fetch dead letter %s, error: %w", deadLetterId, err)
+	}
+	b, err := json.MarshalIndent(e.Data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal dead letter, error: %w", err)
+	}
+	fmt.Fprintln(c.out, string(b))
+	return nil
+}
+
+// requeueDeadLetters marks all failed dead letters of the given scraper and step as requeued and processes their items again
+// using the scraper's current config. Any item that fails again marks its dead letter as failed and any item that
+// no longer exists marks it as missing, after which all dead letters that are still marked as requeued are removed.
+func (c *Cli) requeueDeadLetters(db *database.Db, configs []*config.Config, configId string, scraperId string, step string) error {
+	if step != crawler.StepId && step != filter.StepId {
+		return fmt.Errorf("unknown step %s, expected %s or %s", step, crawler.StepId, filter.StepId)
+	}
+	s, err := findScraper(configs, configId, scraperId)
+	if err != nil {
+		return err
+	}
+	params := map[string]any{"config_id": configId, "scraper_id": scraperId, "step": step}
+	failed := map[string]any{"status": database.DeadLetterFailedStatus}
+	for k, v := range params {
+		failed[k] = v
+	}
+	iterator, err := db.GetMany(database.DeadLetterTableName, failed)
+	if err != nil {
+		return fmt.Errorf("failed to fetch dead letters, error: %w", err)
+	}
+	deadLetters := make([]*database.Entity, 0)
+	for e, _ := iterator.Next(); e != nil; e, _ = iterator.Next() {
+		deadLetters = append(deadLetters, e)
+	}
+	if len(deadLetters) == 0 {
+		fmt.Fprintln(c.out, "No failed dead letters found.")
+		return nil
+	}
+	release, err := acquireLocks(db, []*scraper.Scraper{s})
+	if err != nil {
+		return err
+	}
+	defer release()
+	err = db.UpdateMany(database.DeadLetterTableName, failed, map[string]any{"status": database.DeadLetterRequeuedStatus})
+	if err != nil {
+		return fmt.Errorf("failed to mark dead letters as requeued, error: %w", err)
+	}
+	requeued := map[string]any{"status": database.DeadLetterRequeuedStatus}
+	for k, v := range params {
+		requeued[k] = v
+	}
+	if step == crawler.StepId {
+		err = requeueCalls(db, s, deadLetters)
+	} else {
+		err = requeueDocuments(db, s, deadLetters)
+	}
+	if err != nil {
+		if resetErr := db.UpdateMany(database.DeadLetterTableName, requeued, map[string]any{"status": database.DeadLetterFailedStatus}); resetErr != nil {
+			logger.With("error", resetErr).Errorf("Failed to mark dead letters as failed again")
+		}
+		return err
+	}
+	if err = db.DeleteMany(database.DeadLetterTableName, requeued); err != nil {
+		return fmt.Errorf("failed to remove processed dead letters, error: %w", err)
+	}
+	fmt.Fprintf(c.out, "Requeued %d dead letters.\n", len(deadLetters))
+	return nil
+}
+
+// requeueCalls crawls the calls held by the given dead letters again.
+func requeueCalls(db *database.Db, s *scraper.Scraper, deadLetters []*database.Entity) error {
+	if s.Crawler == nil {
+		return fmt.Errorf("scraper %s does not have a crawler", s.GetScraperId())
+	}
+	calls := make([]*crawler.Call, 0)
+	for _, e := range deadLetters {
+		call, err := crawler.NewCallFromDeadLetter(e)
+		if err != nil {
+			return err
+		}
+		calls = append(calls, call)
+	}
+	m := crawler.NewManager(db)
+	m.RegisterCrawler(s.Crawler, calls)
+	if s.Concurrency != nil {
+		m.SetConcurrency(s.Crawler, s.Concurrency.CrawlWorkers, s.Concurrency.HostWorkers)
+	}
+	m.Start(scraper.WorkerCount(scraper.CrawlerWorkerCountEnv))
+	return nil
+}
+
+// requeueDocuments filters the scraped documents held by the given dead letters again,
+// documents that no longer exist have been purged from the "scraped_data" table, their dead letters are kept
+// and marked as missing.
+func requeueDocuments(db *database.Db, s *scraper.Scraper, deadLetters []*database.Entity) error {
+	if s.Filter == nil {
+		return fmt.Errorf("scraper %s does not have a filter", s.GetScraperId())
+	}
+	entities := make([]*database.Entity, 0)
+	for _, dl := range deadLetters {
+		item, ok := database.AsMap(dl.Data["item"])
+		if !ok {
+			return fmt.Errorf("dead letter %v does not hold a document", dl.Id)
+		}
+		e, err := db.GetOne(database.ScrapedDataTableName, map[string]any{"_id": item["document_id"]})
+		if err == mongo.ErrNoDocuments {
+			logger.With("document_id", item["document_id"]).Warnf("Scraped document no longer exists, marking dead letter as missing")
+			err = db.UpdateMany(database.DeadLetterTableName, map[string]any{"_id": dl.Id}, map[string]any{
+				"status": database.DeadLetterMissingStatus,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to mark dead letter %v as missing, error: %w", dl.Id, err)
+			}
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to fetch scraped document %v, error: %w", item["document_id"], err)
+		}
+		entities = append(entities, e)
+	}
+	m := filter.NewManager(db)
+	provenance, _ := strconv.ParseBool(os.Getenv("FILTER_PROVENANCE"))
+	m.SetProvenance(provenance)
+	m.Reprocess(scraper.WorkerCount(scraper.FilterWorkerCountEnv), s.Filter, entities)
+	return nil
+}
+
+// findScraper looks up the scraper.Scraper matching the given config and scraper ID.
+func findScraper(configs []*config.Config, configId string, scraperId string) (*scraper.Scraper, error) {
+	for _, c := range configs {
+		if c.Id != configId {
+			continue
+		}
+		for _, s := range c.Scrapers {
+			if s.GetScraperId() == scraperId {
+				return s, nil
+			}
+		}
+		return nil, fmt.Errorf("config %s does not have a scraper with ID %s", configId, scraperId)
+	}
+	return nil, fmt.Errorf("no config found with ID %s", configId)
+}
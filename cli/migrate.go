@@ -0,0 +1,23 @@
+package cli
+
+import (
+	"fmt"
+	"github.com/mmaaskant/gro-crop-scraper/logger"
+)
+
+// runMigrate moves the raw bodies of documents stored before raw bodies were compressed to the "raw_bodies" table.
+func runMigrate(c *Cli, cmd *Command, args []string) error {
+	if len(args) > 0 {
+		return usageErrorf("expected no arguments")
+	}
+	db, err := connect()
+	if err != nil {
+		return err
+	}
+	migrated, err := db.MigrateRawBodies()
+	if err != nil {
+		return fmt.Errorf("failed to migrate raw bodies after migrating %d documents, error: %w", migrated, err)
+	}
+	logger.Infof("Migrated the raw bodies of %d documents", migrated)
+	return nil
+}
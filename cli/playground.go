@@ -0,0 +1,100 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/mmaaskant/gro-crop-scraper/crawler"
+	"github.com/mmaaskant/gro-crop-scraper/database"
+	"github.com/mmaaskant/gro-crop-scraper/filter"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// runPlayground runs the filter of a single scraper against a URL, local file or stored document and prints the
+// extracted data along with a trace of every criteria that matched, which allows filter criteria to be debugged
+// without running the whole pipeline.
+func runPlayground(c *Cli, cmd *Command, args []string) error {
+	if len(args) != 3 {
+		return usageErrorf("expected a config ID, scraper ID and source")
+	}
+	configs, err := c.selectConfigs([]string{args[0] + "/" + args[1]})
+	if err != nil {
+		return err
+	}
+	sc, err := findScraper(configs, args[0], args[1])
+	if err != nil {
+		return err
+	}
+	if sc.Filter == nil {
+		return fmt.Errorf("scraper %s of config %s does not have a filter", args[1], args[0])
+	}
+	s, err := readSource(sc.Crawler, args[2])
+	if err != nil {
+		return fmt.Errorf("failed to read source %s, error: %w", args[2], err)
+	}
+	f := sc.Filter.Clone()
+	if tf, ok := f.(filter.Traceable); ok {
+		tf.SetTracer(c.printTraceEvent)
+	}
+	fmt.Fprintln(c.out, "Trace:")
+	data, err := f.Filter(s)
+	if err != nil {
+		return fmt.Errorf("failed to filter source %s, error: %w", args[2], err)
+	}
+	b, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal filtered data, error: %w", err)
+	}
+	fmt.Fprintf(c.out, "Result:\n%s\n", b)
+	return nil
+}
+
+// readSource fetches the given URL using the given crawler.Crawler, so the URL is fetched like it is when crawling,
+// reads the given file or fetches the given document from the "scraped_data" table.
+func readSource(c crawler.Crawler, source string) (string, error) {
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		if c == nil {
+			return "", fmt.Errorf("scraper does not have a crawler to fetch URLs with")
+		}
+		req, err := http.NewRequest(http.MethodGet, source, nil)
+		if err != nil {
+			return "", err
+		}
+		d := c.Crawl(crawler.NewCall(req, crawler.ExtractRequestType))
+		return d.Data, d.Error
+	}
+	if _, err := os.Stat(source); err == nil {
+		b, err := os.ReadFile(source)
+		return string(b), err
+	}
+	db, err := connect()
+	if err != nil {
+		return "", err
+	}
+	id, err := db.ParseId(source)
+	if err != nil {
+		return "", fmt.Errorf("source is not a URL, an existing file or a valid document ID")
+	}
+	e, err := db.GetOne(database.ScrapedDataTableName, map[string]any{"_id": id})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprint(e.Data["data"]), nil
+}
+
+func (c *Cli) printTraceEvent(e *filter.TraceEvent) {
+	action := "matched"
+	if e.Extracted != nil {
+		keys := make([]string, 0)
+		for k := range e.Extracted {
+			keys = append(keys, k)
+		}
+		action = fmt.Sprintf("extracted [%s]", strings.Join(keys, ", "))
+	}
+	text := strings.Join(strings.Fields(e.Text), " ")
+	if len(text) > 120 {
+		text = filter.TruncateText(text, 117) + "..."
+	}
+	fmt.Fprintf(c.out, "  line %d, depth %d: %s %s\n    %s\n", e.Line, e.Depth, action, e.Criteria.Path(), text)
+}
@@ -0,0 +1,203 @@
+package cli
+
+import (
+	"fmt"
+	"github.com/mmaaskant/gro-crop-scraper/config"
+	"github.com/mmaaskant/gro-crop-scraper/database"
+	"github.com/mmaaskant/gro-crop-scraper/logger"
+	"github.com/mmaaskant/gro-crop-scraper/metrics"
+	"github.com/mmaaskant/gro-crop-scraper/scheduler"
+	"github.com/mmaaskant/gro-crop-scraper/scraper"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+)
+
+func runRun(c *Cli, cmd *Command, args []string) error {
+	fs, err := c.parseFlags(cmd, args)
+	if err != nil {
+		return err
+	}
+	steps, err := config.ParseSteps(getString(fs, "steps"))
+	if err != nil {
+		return usageErrorf("%s", err)
+	}
+	return c.runSteps(steps, getBool(fs, "refilter"), fs.Args())
+}
+
+func runCrawl(c *Cli, cmd *Command, args []string) error {
+	fs, err := c.parseFlags(cmd, args)
+	if err != nil {
+		return err
+	}
+	return c.runSteps([]string{config.CrawlMethodStepId}, false, fs.Args())
+}
+
+func runFilter(c *Cli, cmd *Command, args []string) error {
+	fs, err := c.parseFlags(cmd, args)
+	if err != nil {
+		return err
+	}
+	return c.runSteps([]string{config.FilterMethodStepId}, getBool(fs, "refilter"), fs.Args())
+}
+
+func runMap(c *Cli, cmd *Command, args []string) error {
+	fs, err := c.parseFlags(cmd, args)
+	if err != nil {
+		return err
+	}
+	return c.runSteps([]string{config.MapMethodStepId}, false, fs.Args())
+}
+
+func runCompile(c *Cli, cmd *Command, args []string) error {
+	fs, err := c.parseFlags(cmd, args)
+	if err != nil {
+		return err
+	}
+	return c.runSteps([]string{config.CompileMethodStepId}, false, fs.Args())
+}
+
+// runSteps runs the given steps of the configs matching the given selectors once.
+// The map and compile steps have not been implemented yet, so these are skipped and running only those is an error.
+func (c *Cli) runSteps(steps []string, refilter bool, selectors []string) error {
+	configs, err := c.selectConfigs(selectors)
+	if err != nil {
+		return err
+	}
+	if !contains(steps, config.CrawlMethodStepId) && !contains(steps, config.FilterMethodStepId) {
+		if len(steps) > 1 {
+			return fmt.Errorf("the %s steps have not been implemented yet", strings.Join(steps, " and "))
+		}
+		return fmt.Errorf("the %s step has not been implemented yet", steps[0])
+	}
+	ms := startMetricsServer()
+	defer shutdownMetricsServer(ms)
+	db, err := connect()
+	if err != nil {
+		return err
+	}
+	setReady(ms)
+	scrapers := make([]*scraper.Scraper, 0)
+	for _, cfg := range configs {
+		scrapers = append(scrapers, cfg.WithSteps(steps).Scrapers...)
+	}
+	release, err := acquireLocks(db, scrapers)
+	if err != nil {
+		return err
+	}
+	defer release()
+	sm := scraper.NewManager(db)
+	sm.SetRefilter(refilter)
+	sm.SetFlags(c.args)
+	sm.RegisterScrapers(scrapers)
+	sm.Start()
+	return nil
+}
+
+// acquireLocks acquires the lock of every given scraper.Scraper, which the scheduler.Scheduler holds while running
+// a step, and refreshes them until the returned function releases them. An error is returned if any scraper is
+// already running, in which case no locks are held.
+func acquireLocks(db *database.Db, scrapers []*scraper.Scraper) (func(), error) {
+	owner := scheduler.LockOwner()
+	locks := make([]string, 0, len(scrapers))
+	done := make(chan struct{})
+	release := func() {
+		close(done)
+		for _, lock := range locks {
+			if err := db.ReleaseLock(lock, owner); err != nil {
+				logger.With("lock", lock, "error", err).Errorf("Failed to release lock")
+			}
+		}
+	}
+	for _, sc := range scrapers {
+		lock := scheduler.LockName(sc)
+		acquired, err := db.AcquireLock(lock, owner, scheduler.LockTtl)
+		if err == nil && !acquired {
+			err = fmt.Errorf("scraper %s/%s is already running", sc.GetConfigId(), sc.GetScraperId())
+		}
+		if err != nil {
+			release()
+			return nil, fmt.Errorf("failed to acquire lock %s, error: %w", lock, err)
+		}
+		locks = append(locks, lock)
+		scheduler.RefreshLock(db, logger.With("lock", lock), lock, owner, done)
+	}
+	return release, nil
+}
+
+// runDaemon schedules the steps of the selected configs that have a schedule and runs them until the process is
+// interrupted, once interrupted it waits for any running steps to finish.
+func runDaemon(c *Cli, cmd *Command, args []string) error {
+	fs, err := c.parseFlags(cmd, args)
+	if err != nil {
+		return err
+	}
+	configs, err := c.selectConfigs(fs.Args())
+	if err != nil {
+		return err
+	}
+	ms := startMetricsServer()
+	defer shutdownMetricsServer(ms)
+	db, err := connect()
+	if err != nil {
+		return err
+	}
+	s := scheduler.NewScheduler(db)
+	s.SetRefilter(getBool(fs, "refilter"))
+	for _, cfg := range configs {
+		for _, sc := range cfg.Scrapers {
+			for step, spec := range cfg.Schedules {
+				if step == config.CrawlMethodStepId && sc.Crawler == nil || step == config.FilterMethodStepId && sc.Filter == nil {
+					continue
+				}
+				if err := s.Schedule(sc, step, spec); err != nil {
+					return fmt.Errorf("failed to schedule config %s, error: %w", cfg.Id, err)
+				}
+			}
+		}
+	}
+	if ms != nil {
+		ms.Handle("/schedule", s)
+	}
+	setReady(ms)
+	s.Start()
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	<-signals
+	logger.Infof("Stopping daemon, waiting for running steps to finish ...")
+	s.Stop()
+	return nil
+}
+
+// startMetricsServer starts serving metrics and health endpoints if METRICS_ADDR is set, and returns nil otherwise.
+func startMetricsServer() *metrics.Server {
+	addr := os.Getenv("METRICS_ADDR")
+	if addr == "" {
+		return nil
+	}
+	ms := metrics.NewServer(addr)
+	ms.Start()
+	return ms
+}
+
+func setReady(ms *metrics.Server) {
+	if ms != nil {
+		ms.SetReady(true)
+	}
+}
+
+func shutdownMetricsServer(ms *metrics.Server) {
+	if ms != nil {
+		ms.Shutdown()
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
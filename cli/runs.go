@@ -0,0 +1,145 @@
+package cli
+
+import (
+	"fmt"
+	"github.com/mmaaskant/gro-crop-scraper/database"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// maxErrors is the maximum amount of errors printed in a run's summary.
+const maxErrors = 10
+
+// runRuns lists or summarises the runs stored in the "runs" table.
+func runRuns(c *Cli, cmd *Command, args []string) error {
+	var limit int
+	switch {
+	case len(args) > 0 && args[0] == "list" && len(args) <= 2:
+		limit = 20
+		if len(args) == 2 {
+			var err error
+			if limit, err = strconv.Atoi(args[1]); err != nil || limit < 1 {
+				return usageErrorf("invalid limit %s", args[1])
+			}
+		}
+	case len(args) == 2 && args[0] == "show":
+	default:
+		return usageErrorf("expected list or show")
+	}
+	db, err := connect()
+	if err != nil {
+		return err
+	}
+	if args[0] == "list" {
+		return c.listRuns(db, limit)
+	}
+	return c.showRun(db, args[1])
+}
+
+// listRuns prints a single line for each of the most recent runs, newest first.
+func (c *Cli) listRuns(db *database.Db, limit int) error {
+	iterator, err := db.GetMany(database.RunTableName, map[string]any{})
+	if err != nil {
+		return fmt.Errorf("failed to fetch runs, error: %w", err)
+	}
+	runs := make([]*database.Entity, 0)
+	for e, _ := iterator.Next(); e != nil; e, _ = iterator.Next() {
+		runs = append(runs, e)
+	}
+	sort.Slice(runs, func(i, j int) bool {
+		return toTime(runs[i].Data["started_at"]).After(toTime(runs[j].Data["started_at"]))
+	})
+	if len(runs) > limit {
+		runs = runs[:limit]
+	}
+	for _, e := range runs {
+		fmt.Fprintf(c.out, "%v %-8s started: %s duration: %s configs: %v flags: %v\n",
+			e.Id, e.Data["status"], formatTime(e.Data["started_at"]), duration(e), e.Data["configs"], e.Data["flags"],
+		)
+	}
+	return nil
+}
+
+// showRun prints a summary of the run matching the given ID.
+func (c *Cli) showRun(db *database.Db, runId string) error {
+	id, err := db.ParseId(runId)
+	if err != nil {
+		return fmt.Errorf("invalid run ID %s, error: %w", runId, err)
+	}
+	e, err := db.GetOne(database.RunTableName, map[string]any{"_id": id})
+	if err != nil {
+		return fmt.Errorf("failed to fetch run %s, error: %w", runId, err)
+	}
+	fmt.Fprintf(c.out, "Run:      %v\nStatus:   %s\nStarted:  %s\nEnded:    %s\nDuration: %s\nConfigs:  %v\nFlags:    %v\n",
+		e.Id, e.Data["status"], formatTime(e.Data["started_at"]), formatTime(e.Data["ended_at"]), duration(e),
+		e.Data["configs"], e.Data["flags"],
+	)
+	if e.Data["error"] != nil {
+		fmt.Fprintf(c.out, "Error:    %v\n", e.Data["error"])
+	}
+	fmt.Fprintln(c.out, "\nCounters:")
+	counters, _ := database.AsMap(e.Data["counters"])
+	for _, scraperId := range sortedKeys(counters) {
+		steps, _ := database.AsMap(counters[scraperId])
+		for _, step := range sortedKeys(steps) {
+			stepCounters, _ := database.AsMap(steps[step])
+			fmt.Fprintf(c.out, "  %s %s:", scraperId, step)
+			for _, k := range sortedKeys(stepCounters) {
+				fmt.Fprintf(c.out, " %s=%v", k, stepCounters[k])
+			}
+			fmt.Fprintln(c.out)
+		}
+	}
+	iterator, err := db.GetMany(database.ErrorTableName, map[string]any{"run_id": id})
+	if err != nil {
+		return fmt.Errorf("failed to fetch errors of run %s, error: %w", runId, err)
+	}
+	errorCount := 0
+	for ee, _ := iterator.Next(); ee != nil; ee, _ = iterator.Next() {
+		if errorCount == 0 {
+			fmt.Fprintln(c.out, "\nErrors:")
+		}
+		if errorCount < maxErrors {
+			fmt.Fprintf(c.out, "  %s %s %v\n    %v\n", ee.Data["step"], ee.Data["scraper_id"], ee.Data["url"], ee.Data["error"])
+		}
+		errorCount++
+	}
+	if errorCount > maxErrors {
+		fmt.Fprintf(c.out, "  ... and %d more\n", errorCount-maxErrors)
+	}
+	return nil
+}
+
+func duration(e *database.Entity) string {
+	started, ended := toTime(e.Data["started_at"]), toTime(e.Data["ended_at"])
+	if started.IsZero() || ended.IsZero() {
+		return "-"
+	}
+	return ended.Sub(started).Round(time.Second).String()
+}
+
+func formatTime(v any) string {
+	t := toTime(v)
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format(time.RFC3339)
+}
+
+func toTime(v any) time.Time {
+	if dt, ok := v.(primitive.DateTime); ok {
+		return dt.Time()
+	}
+	return time.Time{}
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
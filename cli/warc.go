@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/mmaaskant/gro-crop-scraper/attribute"
+	"github.com/mmaaskant/gro-crop-scraper/database"
+	"github.com/mmaaskant/gro-crop-scraper/logger"
+	"github.com/mmaaskant/gro-crop-scraper/warc"
+	"os"
+	"strings"
+)
+
+// runExport exports the documents stored in the "scraped_data" table to a WARC file.
+func runExport(c *Cli, cmd *Command, args []string) error {
+	fs, err := c.parseFlags(cmd, args)
+	if err != nil {
+		return err
+	}
+	if getString(fs, "config") == "" || fs.NArg() > 0 {
+		return usageErrorf("expected --config and no arguments")
+	}
+	db, err := connect()
+	if err != nil {
+		return err
+	}
+	return export(db, getString(fs, "config"), getString(fs, "scraper"), getString(fs, "run"), getString(fs, "o"))
+}
+
+// runImport imports the responses held by a WARC file into the "scraped_data" table. Imported pages keep the
+// config and scraper they were exported by, unless both are provided.
+func runImport(c *Cli, cmd *Command, args []string) error {
+	fs, err := c.parseFlags(cmd, args)
+	if err != nil {
+		return err
+	}
+	configId, scraperId := getString(fs, "config"), getString(fs, "scraper")
+	if fs.NArg() != 1 || (configId == "") != (scraperId == "") {
+		return usageErrorf("expected a single file, and either both or neither of --config and --scraper")
+	}
+	var tag *attribute.Tag
+	if configId != "" {
+		tag = attribute.NewTag(configId, scraperId)
+	}
+	db, err := connect()
+	if err != nil {
+		return err
+	}
+	return importFile(db, fs.Arg(0), tag)
+}
+
+// export writes all scraped pages of the given config, and optionally scraper and run, to the given file.
+func export(db *database.Db, configId string, scraperId string, runId string, output string) error {
+	if output == "" {
+		output = configId + ".warc.gz"
+	}
+	params := map[string]any{"config_id": configId}
+	if scraperId != "" {
+		params["scraper_id"] = scraperId
+	}
+	if runId != "" {
+		id, err := db.ParseId(runId)
+		if err != nil {
+			return fmt.Errorf("invalid run ID %s, error: %w", runId, err)
+		}
+		params["run_id"] = id
+	}
+	f, err := os.Create(output)
+	if err != nil {
+		return fmt.Errorf("failed to create %s, error: %w", output, err)
+	}
+	defer f.Close()
+	bw := bufio.NewWriter(f)
+	exported, err := warc.Export(db, warc.NewWriter(bw, strings.HasSuffix(output, ".gz")), params)
+	if err != nil {
+		return fmt.Errorf("failed to export after exporting %d pages, error: %w", exported, err)
+	}
+	if err = bw.Flush(); err != nil {
+		return fmt.Errorf("failed to write %s, error: %w", output, err)
+	}
+	if err = f.Close(); err != nil {
+		return fmt.Errorf("failed to close %s, error: %w", output, err)
+	}
+	logger.Infof("Exported %d pages to %s", exported, output)
+	return nil
+}
+
+// importFile stores all responses held by the given file in the "scraped_data" table.
+func importFile(db *database.Db, input string, tag *attribute.Tag) error {
+	f, err := os.Open(input)
+	if err != nil {
+		return fmt.Errorf("failed to open %s, error: %w", input, err)
+	}
+	defer f.Close()
+	r, err := warc.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("failed to read %s, error: %w", input, err)
+	}
+	imported, err := warc.Import(db, r, tag)
+	if err != nil {
+		return fmt.Errorf("failed to import after importing %d pages, error: %w", imported, err)
+	}
+	logger.Infof("Imported %d pages from %s, run the filter step to filter them", imported, input)
+	return nil
+}
@@ -5,6 +5,8 @@ db.scraped_data.createIndex({ config_id: 1 })
 db.scraped_data.createIndex({ scraper_id: 1 })
 db.scraped_data.createIndex({ created_at: 1 })
 db.scraped_data.createIndex({ updated_at: 1 })
+db.scraped_data.createIndex({ body_hash: 1 })
+db.scraped_data.createIndex({ run_id: 1 })
 
 // Set up the "filtered_data" table, which holds the results of the filtered scraped data.
 db.filtered_data.drop()
@@ -12,4 +14,32 @@ db.filtered_data.createIndex({ url: 1 }, { unique: true })
 db.filtered_data.createIndex({ config_id: 1 })
 db.filtered_data.createIndex({ scraper_id: 1 })
 db.filtered_data.createIndex({ created_at: 1 })
-db.filtered_data.createIndex({ updated_at: 1 })
\ No newline at end of file
+db.filtered_data.createIndex({ updated_at: 1 })
+db.filtered_data.createIndex({ run_id: 1 })
+
+// Set up the "raw_bodies" table, which holds compressed raw bodies addressed by the hash of their content.
+db.raw_bodies.drop()
+db.raw_bodies.createIndex({ created_at: 1 })
+db.raw_bodies.createIndex({ updated_at: 1 })
+
+// Set up the "errors" table, which holds failures that occurred while processing a single document or call.
+db.errors.drop()
+db.errors.createIndex({ run_id: 1 })
+db.errors.createIndex({ config_id: 1 })
+db.errors.createIndex({ scraper_id: 1 })
+db.errors.createIndex({ created_at: 1 })
+
+// Set up the "dead_letters" table, which holds items that a step failed to process so they can be requeued.
+db.dead_letters.drop()
+db.dead_letters.createIndex({ step: 1, scraper_id: 1, key: 1 }, { unique: true })
+db.dead_letters.createIndex({ config_id: 1, scraper_id: 1, step: 1 })
+db.dead_letters.createIndex({ status: 1 })
+
+// Set up the "runs" table, which holds a record of every run and its counters.
+db.runs.drop()
+db.runs.createIndex({ started_at: -1 })
+db.runs.createIndex({ status: 1 })
+
+// Set up the "locks" table, which holds the locks preventing processes from running the same work at the same time.
+db.locks.drop()
+db.locks.createIndex({ expires_at: 1 })
\ No newline at end of file
@@ -2,12 +2,8 @@ package config
 
 import (
 	"github.com/mmaaskant/gro-crop-scraper/crawler"
-	"github.com/mmaaskant/gro-crop-scraper/filter"
 	"github.com/mmaaskant/gro-crop-scraper/scraper"
-	"log"
 	"net/http"
-	"reflect"
-	"time"
 )
 
 const (
@@ -18,50 +14,33 @@ const (
 // NewBurpeeConfig holds components configured to scrape https://burpee.com.
 func NewBurpeeConfig() *Config {
 	c := newConfig(BurpeeConfigId)
-	c.AddScraper(BurpeeHtmlScraperId, scraper.NewScraper(newBurpeeHtmlCrawler(), getBurpeeHtmlCrawlerCalls(), getBurpeeHtmlFilter()))
+	site := newBurpeeSite()
+	s := scraper.NewScraper(newBurpeeHtmlCrawler(site), site.NewHtmlCalls(), site.NewHtmlFilter())
+	s.SetConcurrency(scraper.NewConcurrency(10, 4, 3))
+	c.AddScraper(BurpeeHtmlScraperId, s)
+	c.SetSchedule(CrawlMethodStepId, "0 3 * * 1")
+	c.SetSchedule(FilterMethodStepId, "@every 6h")
 	return c
 }
 
-// newBurpeeHtmlCrawler returns an instance of crawler.HtmlCrawler configured to scrape
-// the Burpee website and a slice of crawler.Call instances to kick off the crawling process.
-func newBurpeeHtmlCrawler() *crawler.HtmlCrawler {
-	cr := crawler.NewHtmlCrawler(&http.Client{Timeout: 90 * time.Second})
-	cr.AddDiscoveryUrlRegex(`(https?:\/\/)?www\.burpee\.com\/?(vegetables|flowers|perennials|herbs|fruit)([\w\/-]*)(\?p=\d{1,3})?(&is_scroll=1)?`)
-	cr.AddExtractUrlRegex(`(https?:\/\/)?www\.burpee\.com\/([\w\-]*)(prod\d*.html)(\/)?`)
-	return cr
-}
-
-func getBurpeeHtmlCrawlerCalls() []*crawler.Call {
-	return []*crawler.Call{crawler.NewCall(
-		crawler.NewRequest(http.MethodGet, "https://www.burpee.com", nil),
-		crawler.DiscoverRequestType,
-	)}
+// newBurpeeSite returns the MagentoSite describing the Burpee website, whose product pages end in "prod<id>.html"
+// and whose product attributes are prefixed with "bp_".
+func newBurpeeSite() *MagentoSite {
+	return &MagentoSite{
+		BaseUrl:          "https://www.burpee.com",
+		Categories:       []string{"vegetables", "flowers", "perennials", "herbs", "fruit"},
+		ProductPathRegex: `([\w\-]*)(prod\d*.html)(\/)?`,
+		AttributeRegex:   "(bp_).*|name|description|short_description",
+	}
 }
 
-func getBurpeeHtmlFilter() *filter.HtmlFilter {
-	cb := filter.NewCriteriaBuilder(
-		filter.NewCriteria(
-			nil,
-			filter.NewHtmlTokenTagInterpreter("div"),
-			filter.NewHtmlTokenAttributeInterpreter("class", "product-add-form"),
-		),
-	)
-	cb.AddChild(filter.NewCriteria(
-		filter.NewHtmlTextExtractor("attributes", func(data map[string]any) map[string]any {
-			attributes, ok := data["attributes"].(string)
-			if !ok {
-				log.Printf("Burpee HTML filter expected %s, got %s", reflect.TypeOf(attributes), reflect.TypeOf(data["attributes"]))
-			}
-			f := filter.NewJsonFilter(
-				filter.NewCriteria(
-					filter.NewKeyValueExtractor("", ""),
-					filter.NewKeyValueInterpreter("(bp_).*|name|description|short_description", ""),
-				),
-			)
-			return f.Filter(attributes)
-		}),
-		filter.NewHtmlTokenTagInterpreter("script"),
-		filter.NewHtmlTokenAttributeInterpreter("type", "text/x-magento-init"),
-	))
-	return filter.NewHtmlFilter(cb.Build())
+// newBurpeeHtmlCrawler returns the crawler.HtmlCrawler of the Burpee website,
+// which is blocked once it serves an "Access Denied" page or a status reserved for blocked clients.
+func newBurpeeHtmlCrawler(site *MagentoSite) *crawler.HtmlCrawler {
+	cr := site.NewHtmlCrawler()
+	cr.SetHeader("Accept-Language", "en-US,en;q=0.9")
+	cr.AddBlockDetector(crawler.NewStatusBlockDetector(http.StatusForbidden, http.StatusTooManyRequests))
+	accessDenied, _ := crawler.NewRegexBlockDetector(`(?i)<title>\s*access denied\s*</title>`)
+	cr.AddBlockDetector(accessDenied)
+	return cr
 }
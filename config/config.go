@@ -1,51 +1,176 @@
 package config
 
 import (
-	"flag"
+	"fmt"
 	"github.com/mmaaskant/gro-crop-scraper/attribute"
+	"github.com/mmaaskant/gro-crop-scraper/logger"
+	"github.com/mmaaskant/gro-crop-scraper/scheduler"
 	"github.com/mmaaskant/gro-crop-scraper/scraper"
+	"strings"
 )
 
-var configs = []*Config{
-	NewBurpeeConfig(),
-}
-
-func init() {
-	handleFlags()
+// newGoConfigs returns the configs written in Go, these are built on every call as their components hold state.
+func newGoConfigs() []*Config {
+	return []*Config{
+		NewBurpeeConfig(),
+	}
 }
 
 // Config groups scraper.Scraper configurations under an ID,
 // this ID is passed along scraper.Scraper, its components and any data that it handles.
+// Schedules maps steps to the cron expression or interval they are run on in daemon mode.
 type Config struct {
-	Id       string
-	Scrapers []*scraper.Scraper
+	Id        string
+	Scrapers  []*scraper.Scraper
+	Schedules map[string]string
 }
 
 func newConfig(id string) *Config {
 	return &Config{
 		id,
 		make([]*scraper.Scraper, 0),
+		make(map[string]string),
+	}
+}
+
+// GetConfigs returns the configs written in Go along with all declarative configs found in the given directory,
+// if dir is empty only the configs written in Go are returned.
+// An error is returned if any declarative config is invalid, or if a config ID is used more than once.
+func GetConfigs(dir string) ([]*Config, error) {
+	configs := newGoConfigs()
+	if dir == "" {
+		return configs, nil
+	}
+	loaded, err := LoadConfigs(dir)
+	if err != nil {
+		return nil, err
 	}
+	for _, c := range loaded {
+		for _, registered := range configs {
+			if registered.Id == c.Id {
+				return nil, fmt.Errorf("config ID %s loaded from %s is already registered", c.Id, dir)
+			}
+		}
+		configs = append(configs, c)
+	}
+	return configs, nil
 }
 
-// GetConfigs returns all Config instances that have been flagged, or all of them if none have been flagged.
-func GetConfigs() []*Config {
-	rc := make([]*Config, 0)
+// Select returns the configs matching the given selectors, which are either a config ID, e.g. "burpee",
+// or a config ID and scraper ID, e.g. "burpee/burpee_html", which only selects that scraper of the config.
+// All configs are returned if no selectors are given, an error is returned if a selector does not match anything.
+func Select(configs []*Config, selectors []string) ([]*Config, error) {
+	if len(selectors) == 0 {
+		return configs, nil
+	}
+	all := make(map[string]bool)
+	scraperIds := make(map[string][]string)
+	for _, selector := range selectors {
+		configId, scraperId, _ := strings.Cut(selector, "/")
+		c := findConfig(configs, configId)
+		if c == nil {
+			return nil, fmt.Errorf("no config found with ID %s", configId)
+		}
+		if scraperId == "" {
+			all[configId] = true
+			continue
+		}
+		if c.findScraper(scraperId) == nil {
+			return nil, fmt.Errorf("config %s does not have a scraper with ID %s", configId, scraperId)
+		}
+		scraperIds[configId] = append(scraperIds[configId], scraperId)
+	}
+	selected := make([]*Config, 0)
 	for _, c := range configs {
-		if flagToBool(flag.Lookup(c.Id)) == true {
-			rc = append(rc, c)
+		if !all[c.Id] && scraperIds[c.Id] == nil {
+			continue
+		}
+		sc := c.copy()
+		if !all[c.Id] {
+			sc.Scrapers = make([]*scraper.Scraper, 0, len(scraperIds[c.Id]))
+			for _, s := range c.Scrapers {
+				if contains(scraperIds[c.Id], s.GetScraperId()) {
+					sc.Scrapers = append(sc.Scrapers, s)
+				}
+			}
 		}
+		selected = append(selected, sc)
 	}
-	if len(rc) > 0 {
-		return rc
+	return selected, nil
+}
+
+// WithSteps returns a copy of the Config whose scraper.Scraper instances only hold the components of the given steps,
+// this prevents the steps that were not given from being executed.
+func (c *Config) WithSteps(steps []string) *Config {
+	sc := c.copy()
+	sc.Scrapers = make([]*scraper.Scraper, 0, len(c.Scrapers))
+	for _, s := range c.Scrapers {
+		ss := scraper.NewScraper(nil, nil, nil)
+		if contains(steps, CrawlMethodStepId) {
+			ss.Crawler, ss.Calls = s.Crawler, s.Calls
+		}
+		if contains(steps, FilterMethodStepId) {
+			ss.Filter = s.Filter
+		}
+		ss.SetConcurrency(s.Concurrency)
+		ss.SetTag(s.Tag)
+		sc.Scrapers = append(sc.Scrapers, ss)
 	}
-	return configs
+	return sc
 }
 
-// AddScraper adds a scraper.Scraper and checks if any steps were flagged,
-// if flags are found any steps that have not been flagged are excluded from the scraper.Scraper.
-// This exclusion prevents the step from being executed.
+// SetSchedule sets the cron expression, e.g. "0 3 * * 1", or interval, e.g. "@every 6h", the given step
+// of all its scraper.Scraper instances is run on in daemon mode.
+func (c *Config) SetSchedule(step string, spec string) {
+	if step != CrawlMethodStepId && step != FilterMethodStepId {
+		logger.With("config_id", c.Id, "step", step).Panicf("Config can not schedule an unknown step")
+	}
+	if err := scheduler.ParseSpec(spec); err != nil {
+		logger.With("config_id", c.Id, "step", step).Panicf("Config has an invalid schedule %q, error: %s", spec, err)
+	}
+	c.Schedules[step] = spec
+}
+
+// AddScraper tags the scraper.Scraper with the Config's ID and the given ID, and adds it.
 func (c *Config) AddScraper(id string, s *scraper.Scraper) {
 	s.SetTag(attribute.NewTag(c.Id, id))
 	c.Scrapers = append(c.Scrapers, s)
 }
+
+// copy returns a shallow copy of the Config, which allows its scraper.Scraper instances to be selected
+// without modifying the Config itself.
+func (c *Config) copy() *Config {
+	cc := newConfig(c.Id)
+	cc.Scrapers = append(cc.Scrapers, c.Scrapers...)
+	for step, spec := range c.Schedules {
+		cc.Schedules[step] = spec
+	}
+	return cc
+}
+
+func (c *Config) findScraper(id string) *scraper.Scraper {
+	for _, s := range c.Scrapers {
+		if s.GetScraperId() == id {
+			return s
+		}
+	}
+	return nil
+}
+
+func findConfig(configs []*Config, id string) *Config {
+	for _, c := range configs {
+		if c.Id == id {
+			return c
+		}
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
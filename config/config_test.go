@@ -0,0 +1,98 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConfig_Select(t *testing.T) {
+	configs, err := GetConfigs("testdata/valid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(configs) != 5 {
+		t.Fatalf("Expected the Go config and 4 declarative configs, got %d", len(configs))
+	}
+	selected, err := Select(configs, []string{"rest_example", "burpee/burpee_html"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(selected) != 2 || selected[0].Id != BurpeeConfigId || selected[1].Id != "rest_example" {
+		t.Fatalf("Expected configs to be selected in the order they are registered in, got %v", selected)
+	}
+	if len(selected[0].Scrapers) != 1 || selected[0].Scrapers[0].GetScraperId() != BurpeeHtmlScraperId {
+		t.Errorf("Expected only scraper %s to be selected, got %v", BurpeeHtmlScraperId, selected[0].Scrapers)
+	}
+	for _, selectors := range [][]string{{"unknown"}, {"burpee/unknown"}} {
+		if _, err = Select(configs, selectors); err == nil {
+			t.Errorf("Expected selecting %v to fail", selectors)
+		}
+	}
+}
+
+func TestConfig_WithSteps(t *testing.T) {
+	c := NewBurpeeConfig()
+	steps, err := ParseSteps("filter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := c.WithSteps(steps).Scrapers[0]
+	if s.Crawler != nil || s.Calls != nil || s.Filter == nil {
+		t.Errorf("Expected only the filter step to remain, got crawler %v and filter %v", s.Crawler, s.Filter)
+	}
+	if s.GetScraperId() != BurpeeHtmlScraperId {
+		t.Errorf("Expected the scraper to keep its tag, got %s", s.GetScraperId())
+	}
+	if c.Scrapers[0].Crawler == nil {
+		t.Errorf("Expected the original config to keep its crawler")
+	}
+	if _, err = ParseSteps("crawl,scrape"); err == nil {
+		t.Errorf("Expected parsing an unknown step to fail")
+	}
+}
+
+func TestLoadConfigs(t *testing.T) {
+	if _, err := LoadConfigs("testdata/invalid"); err == nil {
+		t.Errorf("Expected loading invalid configs to fail")
+	}
+	expected := map[string][]string{
+		"broken.yaml": {
+			`scrapers[0].crawler.timeout: invalid duration "ninety seconds", expected a format like "90s" or "500ms"`,
+			"scrapers[0].crawler.extract_url_regexes[0]: invalid regex \"(unclosed\", error: error parsing regexp: missing closing ): `(unclosed`",
+			"scrapers[0].crawler.block_detection.body_regexes[0]: invalid regex \"(captcha\", error: error parsing regexp: missing closing ): `(captcha`",
+			`scrapers[0].calls[0].type: unknown call type "explore", expected "discover" or "extract"`,
+			`scrapers[0].filter.criteria[0].extractor.type: unknown type "html_txt", expected one of: "html_attribute", "html_table", "html_text", "key_value", "regex_capture"`,
+			`scrapers[0].filter.criteria[0].interpreters[0].type: type "key_value" can not be used within a filter of type "html"`,
+			`scrapers[0].concurrency.per_host: must not be negative, got -1`,
+		},
+		"unknown_field.json": {
+			`invalid config testdata/invalid/unknown_field.json: json: unknown field "discovery_url_regex"`,
+		},
+	}
+	entries, err := os.ReadDir("testdata/invalid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		_, err := LoadConfig(filepath.Join("testdata/invalid", entry.Name()))
+		var problems []string
+		var de *DefinitionError
+		switch {
+		case errors.As(err, &de):
+			problems = de.Problems
+		case err != nil:
+			problems = []string{err.Error()}
+		}
+		if !reflect.DeepEqual(problems, expected[entry.Name()]) {
+			t.Errorf("Got problems for %s:\n%s\nexpected:\n%s", entry.Name(),
+				strings.Join(problems, "\n"), strings.Join(expected[entry.Name()], "\n"))
+		}
+	}
+	if _, err := GetConfigs("testdata/golden"); err != nil {
+		t.Errorf("Expected a directory without config files to load, got %s", err)
+	}
+}
@@ -0,0 +1,908 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/mmaaskant/gro-crop-scraper/crawler"
+	"github.com/mmaaskant/gro-crop-scraper/filter"
+	"github.com/mmaaskant/gro-crop-scraper/helper"
+	"github.com/mmaaskant/gro-crop-scraper/logger"
+	"github.com/mmaaskant/gro-crop-scraper/scheduler"
+	"github.com/mmaaskant/gro-crop-scraper/scraper"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	HtmlCrawlerType = "html"
+	RestCrawlerType = "rest"
+
+	PagePaginationType       = "page"
+	OffsetPaginationType     = "offset"
+	CursorPaginationType     = "cursor"
+	LinkHeaderPaginationType = "link_header"
+
+	HtmlFilterType = "html"
+	JsonFilterType = "json"
+
+	HtmlTagInterpreterType       = "html_tag"
+	HtmlAttributeInterpreterType = "html_attribute"
+	HtmlTextInterpreterType      = "html_text"
+	KeyValueInterpreterType      = "key_value"
+	AnyInterpreterType           = "any"
+	AllInterpreterType           = "all"
+	NotInterpreterType           = "not"
+
+	HtmlTextExtractorType      = "html_text"
+	HtmlAttributeExtractorType = "html_attribute"
+	HtmlTableExtractorType     = "html_table"
+	KeyValueExtractorType      = "key_value"
+	RegexCaptureExtractorType  = "regex_capture"
+)
+
+// Definition describes a Config in a declarative format, allowing it to be loaded from a YAML or JSON file.
+// Schedule maps steps to the cron expression or interval they are run on in daemon mode.
+type Definition struct {
+	Id       string               `yaml:"id" json:"id"`
+	Schedule map[string]string    `yaml:"schedule" json:"schedule"`
+	Scrapers []*ScraperDefinition `yaml:"scrapers" json:"scrapers"`
+}
+
+// ScraperDefinition describes a scraper.Scraper and its components,
+// a scraper of a Shopify or Magento 2 shop only requires Shopify or Magento as its components are built from them.
+type ScraperDefinition struct {
+	Id          string                 `yaml:"id" json:"id"`
+	Shopify     *ShopifyDefinition     `yaml:"shopify" json:"shopify"`
+	Magento     *MagentoDefinition     `yaml:"magento" json:"magento"`
+	Crawler     *CrawlerDefinition     `yaml:"crawler" json:"crawler"`
+	Calls       []*CallDefinition      `yaml:"calls" json:"calls"`
+	Filter      *FilterDefinition      `yaml:"filter" json:"filter"`
+	Concurrency *ConcurrencyDefinition `yaml:"concurrency" json:"concurrency"`
+}
+
+// ShopifyDefinition describes the Shopify shop or collection at Url and the ShopifyMapping of its products,
+// Tags maps tag labels and Metafields maps "namespace.key" names to the keys they are stored under.
+type ShopifyDefinition struct {
+	Url        string            `yaml:"url" json:"url"`
+	Tags       map[string]string `yaml:"tags" json:"tags"`
+	Metafields map[string]string `yaml:"metafields" json:"metafields"`
+}
+
+// MagentoDefinition describes a MagentoSite, its product pages are crawled unless Rest is set,
+// in which case its products are crawled using its REST API.
+type MagentoDefinition struct {
+	Url              string                 `yaml:"url" json:"url"`
+	Categories       []string               `yaml:"categories" json:"categories"`
+	ProductPathRegex string                 `yaml:"product_path_regex" json:"product_path_regex"`
+	AttributeRegex   string                 `yaml:"attribute_regex" json:"attribute_regex"`
+	Rest             *MagentoRestDefinition `yaml:"rest" json:"rest"`
+}
+
+// MagentoRestDefinition describes the REST API of a MagentoSite, Url defaults to the shop's URL followed by "/rest/V1".
+// Env variables within the token are expanded, e.g. "${EXAMPLE_MAGENTO_TOKEN}".
+type MagentoRestDefinition struct {
+	Url   string `yaml:"url" json:"url"`
+	Token string `yaml:"token" json:"token"`
+}
+
+// ConcurrencyDefinition describes a scraper.Concurrency, any limit that is left out or 0 uses its default.
+type ConcurrencyDefinition struct {
+	Crawl   int `yaml:"crawl" json:"crawl"`
+	PerHost int `yaml:"per_host" json:"per_host"`
+	Filter  int `yaml:"filter" json:"filter"`
+}
+
+// CrawlerDefinition describes a crawler.Crawler and the http.Client it uses,
+// Timeout and RateLimit are formatted as a time.Duration, e.g. "90s" or "500ms".
+// Headers are sent along with every request, and Session is started before the first request is made.
+// Requests are spread over Proxies and UserAgents if set, env variables within proxy URLs are expanded.
+// Responses classified as blocked by BlockDetection are not stored and pause requests to their host.
+// MaxBodySize is the largest response body in bytes that is read, it uses its default if left out.
+// Pagination and the URL fields only apply to rest crawlers, the URL regexes only to html crawlers.
+type CrawlerDefinition struct {
+	Type                string                    `yaml:"type" json:"type"`
+	Timeout             string                    `yaml:"timeout" json:"timeout"`
+	RateLimit           string                    `yaml:"rate_limit" json:"rate_limit"`
+	MaxBodySize         int64                     `yaml:"max_body_size" json:"max_body_size"`
+	Headers             map[string]string         `yaml:"headers" json:"headers"`
+	Session             *SessionDefinition        `yaml:"session" json:"session"`
+	Proxies             []string                  `yaml:"proxies" json:"proxies"`
+	UserAgents          []string                  `yaml:"user_agents" json:"user_agents"`
+	BlockDetection      *BlockDetectionDefinition `yaml:"block_detection" json:"block_detection"`
+	DiscoveryUrlRegexes []string                  `yaml:"discovery_url_regexes" json:"discovery_url_regexes"`
+	ExtractUrlRegexes   []string                  `yaml:"extract_url_regexes" json:"extract_url_regexes"`
+	Pagination          *PaginationDefinition     `yaml:"pagination" json:"pagination"`
+	DiscoveryUrlFields  []*UrlFieldDefinition     `yaml:"discovery_url_fields" json:"discovery_url_fields"`
+	ExtractUrlFields    []*UrlFieldDefinition     `yaml:"extract_url_fields" json:"extract_url_fields"`
+}
+
+// PaginationDefinition describes a crawler.Paginator, the page and offset types increment Param by Step starting
+// at Start until the list found at Items is empty, Step is the amount of items per page for the offset type.
+// The cursor type sends the value found at Cursor as Param, or follows it as the next page URL if Param is left out.
+// The link_header type follows the "next" URL of the Link header. Items and Cursor are dot separated JSON paths.
+type PaginationDefinition struct {
+	Type   string `yaml:"type" json:"type"`
+	Param  string `yaml:"param" json:"param"`
+	Start  *int   `yaml:"start" json:"start"`
+	Step   int    `yaml:"step" json:"step"`
+	Items  string `yaml:"items" json:"items"`
+	Cursor string `yaml:"cursor" json:"cursor"`
+}
+
+// UrlFieldDefinition describes a dot separated JSON path to fields holding URLs, e.g. "products.*.url",
+// or values inserted into Template in place of "{value}", e.g. "https://api.example.com/products/{value}".
+type UrlFieldDefinition struct {
+	Path     string `yaml:"path" json:"path"`
+	Template string `yaml:"template" json:"template"`
+}
+
+// SessionDefinition describes a crawler.HttpSession, its requests are made in order to start the session,
+// which expires once a response has one of the ExpiredStatusCodes or its body or URL matches ExpiredRegex.
+type SessionDefinition struct {
+	Requests           []*SessionRequestDefinition `yaml:"requests" json:"requests"`
+	ExpiredStatusCodes []int                       `yaml:"expired_status_codes" json:"expired_status_codes"`
+	ExpiredRegex       string                      `yaml:"expired_regex" json:"expired_regex"`
+}
+
+// SessionRequestDefinition describes a request made to start a session, if Form is set it is posted url encoded.
+// Env variables within the URL and form values are expanded, e.g. "${BURPEE_PASSWORD}", so credentials
+// do not have to be stored within the config file.
+type SessionRequestDefinition struct {
+	Method string            `yaml:"method" json:"method"`
+	Url    string            `yaml:"url" json:"url"`
+	Form   map[string]string `yaml:"form" json:"form"`
+}
+
+// BlockDetectionDefinition describes the crawler.BlockDetector instances of a crawler, a response is blocked if it has
+// one of the StatusCodes, its body matches any of the BodyRegexes or the body of a page to be extracted does not match
+// ExpectedMarker. CoolDown and MaxCoolDown are formatted as a time.Duration and use their defaults if left out.
+type BlockDetectionDefinition struct {
+	StatusCodes    []int    `yaml:"status_codes" json:"status_codes"`
+	BodyRegexes    []string `yaml:"body_regexes" json:"body_regexes"`
+	ExpectedMarker string   `yaml:"expected_marker" json:"expected_marker"`
+	CoolDown       string   `yaml:"cool_down" json:"cool_down"`
+	MaxCoolDown    string   `yaml:"max_cool_down" json:"max_cool_down"`
+}
+
+// CallDefinition describes a crawler.Call used to kick off the crawling process.
+type CallDefinition struct {
+	Method string `yaml:"method" json:"method"`
+	Url    string `yaml:"url" json:"url"`
+	Type   string `yaml:"type" json:"type"`
+}
+
+// FilterDefinition describes a filter.Filter and its filter.Criteria.
+type FilterDefinition struct {
+	Type     string                `yaml:"type" json:"type"`
+	Criteria []*CriteriaDefinition `yaml:"criteria" json:"criteria"`
+}
+
+// CriteriaDefinition describes a filter.Criteria, its filter.ConditionInterpreter instances, an optional filter.Extractor,
+// its children and its siblings. Child is a shorthand for a single child.
+type CriteriaDefinition struct {
+	Interpreters []*InterpreterDefinition `yaml:"interpreters" json:"interpreters"`
+	Extractor    *ExtractorDefinition     `yaml:"extractor" json:"extractor"`
+	Child        *CriteriaDefinition      `yaml:"child" json:"child"`
+	Children     []*CriteriaDefinition    `yaml:"children" json:"children"`
+	Siblings     []*CriteriaDefinition    `yaml:"siblings" json:"siblings"`
+}
+
+// InterpreterDefinition describes a filter.ConditionInterpreter, Expr is used to match HTML tags and text,
+// while Key and Value are used to match HTML attributes and JSON key value pairs.
+// Interpreters holds the interpreters combined by the any, all and not interpreter types.
+type InterpreterDefinition struct {
+	Type         string                   `yaml:"type" json:"type"`
+	Expr         string                   `yaml:"expr" json:"expr"`
+	Key          string                   `yaml:"key" json:"key"`
+	Value        string                   `yaml:"value" json:"value"`
+	Interpreters []*InterpreterDefinition `yaml:"interpreters" json:"interpreters"`
+}
+
+// ExtractorDefinition describes a filter.Extractor, if a Filter is provided it is run on every extracted string
+// and its results replace the extracted data. Expr holds the regex with named capture groups used by regex_capture.
+type ExtractorDefinition struct {
+	Type    string            `yaml:"type" json:"type"`
+	Id      string            `yaml:"id" json:"id"`
+	Key     string            `yaml:"key" json:"key"`
+	Value   string            `yaml:"value" json:"value"`
+	Expr    string            `yaml:"expr" json:"expr"`
+	Aliases map[string]string `yaml:"aliases" json:"aliases"`
+	Filter  *FilterDefinition `yaml:"filter" json:"filter"`
+}
+
+// DefinitionError holds every problem found while validating a Definition.
+type DefinitionError struct {
+	Source   string
+	Problems []string
+}
+
+func (de *DefinitionError) Error() string {
+	return fmt.Sprintf("invalid config %s:\n\t%s", de.Source, strings.Join(de.Problems, "\n\t"))
+}
+
+// definitionBuilder validates a Definition and builds a Config based on it,
+// any problems found along the way are collected so all of them can be reported at once.
+type definitionBuilder struct {
+	problems []string
+}
+
+func (db *definitionBuilder) addProblem(path string, format string, args ...any) {
+	db.problems = append(db.problems, fmt.Sprintf("%s: %s", path, fmt.Sprintf(format, args...)))
+}
+
+// Build validates the Definition and returns a Config, or a *DefinitionError if the Definition is invalid.
+func (d *Definition) Build(source string) (*Config, error) {
+	db := &definitionBuilder{}
+	if d.Id == "" {
+		db.addProblem("id", "is required")
+	}
+	if len(d.Scrapers) == 0 {
+		db.addProblem("scrapers", "at least one scraper is required")
+	}
+	c := newConfig(d.Id)
+	steps := make([]string, 0, len(d.Schedule))
+	for step := range d.Schedule {
+		steps = append(steps, step)
+	}
+	sort.Strings(steps)
+	for _, step := range steps {
+		path := fmt.Sprintf("schedule.%s", step)
+		if step != CrawlMethodStepId && step != FilterMethodStepId {
+			db.addProblem(path, "unknown step, expected %q or %q", CrawlMethodStepId, FilterMethodStepId)
+		} else if err := scheduler.ParseSpec(d.Schedule[step]); err != nil {
+			db.addProblem(path, "invalid schedule %q: %s", d.Schedule[step], err)
+		} else {
+			c.Schedules[step] = d.Schedule[step]
+		}
+	}
+	scraperIds := make(map[string]bool)
+	for i, sd := range d.Scrapers {
+		path := fmt.Sprintf("scrapers[%d]", i)
+		if sd == nil {
+			db.addProblem(path, "is empty")
+			continue
+		}
+		if sd.Id == "" {
+			db.addProblem(path+".id", "is required")
+		} else if scraperIds[sd.Id] {
+			db.addProblem(path+".id", "scraper %s is defined more than once", sd.Id)
+		}
+		scraperIds[sd.Id] = true
+		if s := db.buildScraper(path, sd); s != nil {
+			c.AddScraper(sd.Id, s)
+		}
+	}
+	if len(db.problems) > 0 {
+		return nil, &DefinitionError{source, db.problems}
+	}
+	return c, nil
+}
+
+func (db *definitionBuilder) buildScraper(path string, sd *ScraperDefinition) *scraper.Scraper {
+	if sd.Shopify != nil {
+		return db.buildShopifyScraper(path, sd)
+	}
+	if sd.Magento != nil {
+		return db.buildMagentoScraper(path, sd)
+	}
+	if sd.Crawler == nil && sd.Filter == nil {
+		db.addProblem(path, "requires a crawler and/or a filter")
+	}
+	var cr crawler.Crawler
+	var calls []*crawler.Call
+	if sd.Crawler != nil {
+		cr = db.buildCrawler(path+".crawler", sd.Crawler)
+		if len(sd.Calls) == 0 {
+			db.addProblem(path+".calls", "at least one call is required when a crawler is defined")
+		}
+		for i, cd := range sd.Calls {
+			if call := db.buildCall(fmt.Sprintf("%s.calls[%d]", path, i), cd); call != nil {
+				calls = append(calls, call)
+			}
+		}
+	}
+	var f filter.Filter
+	if sd.Filter != nil {
+		f = db.buildFilter(path+".filter", sd.Filter)
+	}
+	s := scraper.NewScraper(cr, calls, f)
+	if sd.Concurrency != nil {
+		s.SetConcurrency(db.buildConcurrency(path+".concurrency", sd.Concurrency))
+	}
+	return s
+}
+
+func (db *definitionBuilder) buildShopifyScraper(path string, sd *ScraperDefinition) *scraper.Scraper {
+	if sd.Crawler != nil || len(sd.Calls) > 0 || sd.Filter != nil || sd.Magento != nil {
+		db.addProblem(path+".shopify", "a crawler, calls, filter or magento can not be defined along with shopify")
+	}
+	if !db.validateUrl(path+".shopify.url", sd.Shopify.Url) {
+		return nil
+	}
+	m := NewShopifyMapping()
+	for label, key := range sd.Shopify.Tags {
+		m.AddTag(label, key)
+	}
+	names := make([]string, 0, len(sd.Shopify.Metafields))
+	for name := range sd.Shopify.Metafields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !strings.Contains(name, ".") {
+			db.addProblem(path+".shopify.metafields", "expected a name formatted as \"namespace.key\", got %q", name)
+		}
+		m.AddMetafield(name, sd.Shopify.Metafields[name])
+	}
+	s := NewShopifyScraper(sd.Shopify.Url, m)
+	if sd.Concurrency != nil {
+		s.SetConcurrency(db.buildConcurrency(path+".concurrency", sd.Concurrency))
+	}
+	return s
+}
+
+func (db *definitionBuilder) buildMagentoScraper(path string, sd *ScraperDefinition) *scraper.Scraper {
+	md := sd.Magento
+	if sd.Crawler != nil || len(sd.Calls) > 0 || sd.Filter != nil {
+		db.addProblem(path+".magento", "a crawler, calls or filter can not be defined along with magento")
+	}
+	valid := db.validateUrl(path+".magento.url", md.Url)
+	if md.ProductPathRegex != "" && !db.validateRegex(path+".magento.product_path_regex", md.ProductPathRegex) {
+		valid = false
+	}
+	if md.AttributeRegex == "" {
+		db.addProblem(path+".magento.attribute_regex", "is required")
+		valid = false
+	} else if !db.validateRegex(path+".magento.attribute_regex", md.AttributeRegex) {
+		valid = false
+	}
+	for i, category := range md.Categories {
+		if !db.validateRegex(fmt.Sprintf("%s.magento.categories[%d]", path, i), category) {
+			valid = false
+		}
+	}
+	site := &MagentoSite{
+		BaseUrl:          md.Url,
+		Categories:       md.Categories,
+		ProductPathRegex: md.ProductPathRegex,
+		AttributeRegex:   md.AttributeRegex,
+	}
+	if md.Rest != nil {
+		if md.Rest.Url != "" && !db.validateUrl(path+".magento.rest.url", md.Rest.Url) {
+			valid = false
+		}
+		site.RestUrl = md.Rest.Url
+		site.RestToken = os.ExpandEnv(md.Rest.Token)
+	}
+	if !valid {
+		return nil
+	}
+	var s *scraper.Scraper
+	if md.Rest != nil {
+		s = site.NewRestScraper()
+	} else {
+		s = scraper.NewScraper(site.NewHtmlCrawler(), site.NewHtmlCalls(), site.NewHtmlFilter())
+	}
+	if sd.Concurrency != nil {
+		s.SetConcurrency(db.buildConcurrency(path+".concurrency", sd.Concurrency))
+	}
+	return s
+}
+
+// validateUrl checks if the given URL is an absolute http(s) URL.
+func (db *definitionBuilder) validateUrl(path string, rawUrl string) bool {
+	u, err := url.Parse(rawUrl)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		db.addProblem(path, "invalid url %q", rawUrl)
+		return false
+	}
+	return true
+}
+
+func (db *definitionBuilder) buildConcurrency(path string, cd *ConcurrencyDefinition) *scraper.Concurrency {
+	keys := []string{"crawl", "per_host", "filter"}
+	for i, limit := range []int{cd.Crawl, cd.PerHost, cd.Filter} {
+		if limit < 0 {
+			db.addProblem(path+"."+keys[i], "must not be negative, got %d", limit)
+		}
+	}
+	return scraper.NewConcurrency(cd.Crawl, cd.PerHost, cd.Filter)
+}
+
+// sessionCrawler is implemented by all crawler.Crawler types that can be built from a CrawlerDefinition.
+type sessionCrawler interface {
+	crawler.Crawler
+	SetRateLimit(interval time.Duration)
+	SetHeader(key string, value string)
+	SetSession(s crawler.Session)
+	SetMaxBodySize(size int64)
+	AddBlockDetector(d crawler.BlockDetector)
+	SetCoolDown(base time.Duration, max time.Duration)
+}
+
+func (db *definitionBuilder) buildCrawler(path string, cd *CrawlerDefinition) crawler.Crawler {
+	client := &http.Client{Timeout: db.parseDuration(path+".timeout", cd.Timeout)}
+	if len(cd.Proxies) > 0 || len(cd.UserAgents) > 0 {
+		client.Transport = db.buildTransport(path, cd)
+	}
+	var cr sessionCrawler
+	switch cd.Type {
+	case HtmlCrawlerType:
+		hc := crawler.NewHtmlCrawler(client)
+		for i, expr := range cd.DiscoveryUrlRegexes {
+			if db.validateRegex(fmt.Sprintf("%s.discovery_url_regexes[%d]", path, i), expr) {
+				hc.AddDiscoveryUrlRegex(expr)
+			}
+		}
+		for i, expr := range cd.ExtractUrlRegexes {
+			if db.validateRegex(fmt.Sprintf("%s.extract_url_regexes[%d]", path, i), expr) {
+				hc.AddExtractUrlRegex(expr)
+			}
+		}
+		if len(cd.ExtractUrlRegexes) == 0 {
+			db.addProblem(path+".extract_url_regexes", "at least one regex is required for an %s crawler", HtmlCrawlerType)
+		}
+		if cd.Pagination != nil || len(cd.DiscoveryUrlFields) > 0 || len(cd.ExtractUrlFields) > 0 {
+			db.addProblem(path+".type", "pagination and URL fields require a %s crawler", RestCrawlerType)
+		}
+		cr = hc
+	case RestCrawlerType:
+		rc := crawler.NewRestCrawler(client)
+		if cd.Pagination != nil {
+			if p := db.buildPaginator(path+".pagination", cd.Pagination); p != nil {
+				rc.SetPaginator(p)
+			}
+		}
+		for i, fd := range cd.DiscoveryUrlFields {
+			if db.validateUrlField(fmt.Sprintf("%s.discovery_url_fields[%d]", path, i), fd) {
+				rc.AddDiscoveryUrlField(fd.Path, fd.Template)
+			}
+		}
+		for i, fd := range cd.ExtractUrlFields {
+			if db.validateUrlField(fmt.Sprintf("%s.extract_url_fields[%d]", path, i), fd) {
+				rc.AddExtractUrlField(fd.Path, fd.Template)
+			}
+		}
+		cr = rc
+	default:
+		db.addProblem(path+".type", "unknown crawler type %q, expected %q or %q", cd.Type, HtmlCrawlerType, RestCrawlerType)
+		return nil
+	}
+	cr.SetRateLimit(db.parseDuration(path+".rate_limit", cd.RateLimit))
+	if cd.MaxBodySize < 0 {
+		db.addProblem(path+".max_body_size", "must not be negative, got %d", cd.MaxBodySize)
+	} else if cd.MaxBodySize > 0 {
+		cr.SetMaxBodySize(cd.MaxBodySize)
+	}
+	for key, value := range cd.Headers {
+		cr.SetHeader(key, value)
+	}
+	if cd.Session != nil {
+		if s := db.buildSession(path+".session", cd.Session); s != nil {
+			cr.SetSession(s)
+		}
+	}
+	if cd.BlockDetection != nil {
+		db.buildBlockDetection(path+".block_detection", cd.BlockDetection, cr)
+	}
+	return cr
+}
+
+// buildTransport returns a crawler.RotatingTransport spreading requests over the CrawlerDefinition's proxies
+// and User-Agent strings.
+func (db *definitionBuilder) buildTransport(path string, cd *CrawlerDefinition) http.RoundTripper {
+	proxies := make([]string, 0, len(cd.Proxies))
+	for i, proxy := range cd.Proxies {
+		proxy = os.ExpandEnv(proxy)
+		if _, err := crawler.ParseProxyUrl(proxy); err != nil {
+			db.addProblem(fmt.Sprintf("%s.proxies[%d]", path, i), "invalid proxy url %q", cd.Proxies[i])
+			continue
+		}
+		proxies = append(proxies, proxy)
+	}
+	for i, userAgent := range cd.UserAgents {
+		if strings.TrimSpace(userAgent) == "" {
+			db.addProblem(fmt.Sprintf("%s.user_agents[%d]", path, i), "is empty")
+		}
+	}
+	rt, _ := crawler.NewRotatingTransport(proxies, cd.UserAgents)
+	return rt
+}
+
+func (db *definitionBuilder) buildSession(path string, sd *SessionDefinition) *crawler.HttpSession {
+	s := crawler.NewHttpSession()
+	if len(sd.Requests) == 0 {
+		db.addProblem(path+".requests", "at least one request is required")
+	}
+	for i, rd := range sd.Requests {
+		rPath := fmt.Sprintf("%s.requests[%d]", path, i)
+		if rd == nil {
+			db.addProblem(rPath, "is empty")
+			continue
+		}
+		u, err := url.Parse(os.ExpandEnv(rd.Url))
+		if err != nil || u.Host == "" {
+			db.addProblem(rPath+".url", "invalid url %q", rd.Url)
+			continue
+		}
+		method := strings.ToUpper(rd.Method)
+		if rd.Form != nil {
+			if method != "" && method != http.MethodPost {
+				db.addProblem(rPath+".method", "a form can only be sent using %s, got %q", http.MethodPost, rd.Method)
+				continue
+			}
+			values := make(url.Values)
+			for key, value := range rd.Form {
+				values.Set(key, os.ExpandEnv(value))
+			}
+			s.AddForm(u.String(), values)
+			continue
+		}
+		if method == "" {
+			method = http.MethodGet
+		}
+		s.AddRequest(method, u.String())
+	}
+	for i, code := range sd.ExpiredStatusCodes {
+		if code < 100 || code > 599 {
+			db.addProblem(fmt.Sprintf("%s.expired_status_codes[%d]", path, i), "invalid status code %d", code)
+		}
+	}
+	s.SetExpiredStatusCodes(sd.ExpiredStatusCodes...)
+	if sd.ExpiredRegex != "" && db.validateRegex(path+".expired_regex", sd.ExpiredRegex) {
+		_ = s.SetExpiredRegex(sd.ExpiredRegex)
+	}
+	return s
+}
+
+func (db *definitionBuilder) buildPaginator(path string, pd *PaginationDefinition) crawler.Paginator {
+	switch pd.Type {
+	case PagePaginationType, OffsetPaginationType:
+		param, start, step := "page", 1, 1
+		if pd.Type == OffsetPaginationType {
+			param, start, step = "offset", 0, pd.Step
+			if pd.Step <= 0 {
+				db.addProblem(path+".step", "the amount of items per page is required for the %s type", OffsetPaginationType)
+				return nil
+			}
+		} else if pd.Step < 0 {
+			db.addProblem(path+".step", "must not be negative, got %d", pd.Step)
+			return nil
+		} else if pd.Step > 0 {
+			step = pd.Step
+		}
+		if pd.Param != "" {
+			param = pd.Param
+		}
+		if pd.Start != nil {
+			start = *pd.Start
+		}
+		return crawler.NewParamPaginator(param, start, step, pd.Items)
+	case CursorPaginationType:
+		if pd.Cursor == "" {
+			db.addProblem(path+".cursor", "the path of the cursor is required for the %s type", CursorPaginationType)
+			return nil
+		}
+		return crawler.NewCursorPaginator(pd.Cursor, pd.Param)
+	case LinkHeaderPaginationType:
+		return crawler.NewLinkHeaderPaginator()
+	default:
+		db.addProblem(path+".type", "unknown pagination type %q, expected %q, %q, %q or %q", pd.Type,
+			PagePaginationType, OffsetPaginationType, CursorPaginationType, LinkHeaderPaginationType)
+		return nil
+	}
+}
+
+func (db *definitionBuilder) validateUrlField(path string, fd *UrlFieldDefinition) bool {
+	if fd == nil || fd.Path == "" {
+		db.addProblem(path+".path", "is required")
+		return false
+	}
+	if fd.Template != "" && !strings.Contains(fd.Template, crawler.UrlFieldValue) {
+		db.addProblem(path+".template", "must contain %q, got %q", crawler.UrlFieldValue, fd.Template)
+		return false
+	}
+	return true
+}
+
+// buildBlockDetection adds the crawler.BlockDetector instances described by the BlockDetectionDefinition to cr
+// and sets its cool-down.
+func (db *definitionBuilder) buildBlockDetection(path string, bd *BlockDetectionDefinition, cr sessionCrawler) {
+	if len(bd.StatusCodes) == 0 && len(bd.BodyRegexes) == 0 && bd.ExpectedMarker == "" {
+		db.addProblem(path, "at least one of status_codes, body_regexes or expected_marker is required")
+	}
+	for i, code := range bd.StatusCodes {
+		if code < 100 || code > 599 {
+			db.addProblem(fmt.Sprintf("%s.status_codes[%d]", path, i), "invalid status code %d", code)
+		}
+	}
+	if len(bd.StatusCodes) > 0 {
+		cr.AddBlockDetector(crawler.NewStatusBlockDetector(bd.StatusCodes...))
+	}
+	for i, expr := range bd.BodyRegexes {
+		if db.validateRegex(fmt.Sprintf("%s.body_regexes[%d]", path, i), expr) {
+			d, _ := crawler.NewRegexBlockDetector(expr)
+			cr.AddBlockDetector(d)
+		}
+	}
+	if bd.ExpectedMarker != "" && db.validateRegex(path+".expected_marker", bd.ExpectedMarker) {
+		d, _ := crawler.NewMarkerBlockDetector(bd.ExpectedMarker)
+		cr.AddBlockDetector(d)
+	}
+	coolDown := db.parseDuration(path+".cool_down", bd.CoolDown)
+	if coolDown == 0 {
+		coolDown = crawler.DefaultCoolDown
+	}
+	maxCoolDown := db.parseDuration(path+".max_cool_down", bd.MaxCoolDown)
+	if maxCoolDown == 0 {
+		maxCoolDown = crawler.DefaultMaxCoolDown
+	}
+	if maxCoolDown < coolDown {
+		db.addProblem(path+".max_cool_down", "must not be shorter than cool_down %s, got %s", coolDown, maxCoolDown)
+	}
+	cr.SetCoolDown(coolDown, maxCoolDown)
+}
+
+func (db *definitionBuilder) buildCall(path string, cd *CallDefinition) *crawler.Call {
+	if cd == nil {
+		db.addProblem(path, "is empty")
+		return nil
+	}
+	method := strings.ToUpper(cd.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+	requestType := strings.ToUpper(cd.Type)
+	switch requestType {
+	case "":
+		requestType = crawler.DiscoverRequestType
+	case crawler.DiscoverRequestType, crawler.ExtractRequestType:
+	default:
+		db.addProblem(path+".type", "unknown call type %q, expected %q or %q",
+			cd.Type, strings.ToLower(crawler.DiscoverRequestType), strings.ToLower(crawler.ExtractRequestType))
+		return nil
+	}
+	req, err := http.NewRequest(method, cd.Url, nil)
+	if err != nil || req.URL.Host == "" {
+		db.addProblem(path+".url", "invalid url %q", cd.Url)
+		return nil
+	}
+	return crawler.NewCall(req, requestType)
+}
+
+func (db *definitionBuilder) buildFilter(path string, fd *FilterDefinition) filter.Filter {
+	if len(fd.Criteria) == 0 {
+		db.addProblem(path+".criteria", "at least one criteria is required")
+	}
+	criteria := make([]*filter.Criteria, 0)
+	for i, cd := range fd.Criteria {
+		if c := db.buildCriteria(fmt.Sprintf("%s.criteria[%d]", path, i), fd.Type, cd); c != nil {
+			criteria = append(criteria, c)
+		}
+	}
+	// The definition itself is used as revision, as the nested filters it holds are hidden within Clean functions.
+	revision, _ := json.Marshal(fd)
+	switch fd.Type {
+	case HtmlFilterType:
+		f := filter.NewHtmlFilter(criteria...)
+		f.SetRevision(string(revision))
+		return f
+	case JsonFilterType:
+		f := filter.NewJsonFilter(criteria...)
+		f.SetRevision(string(revision))
+		return f
+	default:
+		db.addProblem(path+".type", "unknown filter type %q, expected %q or %q", fd.Type, HtmlFilterType, JsonFilterType)
+		return nil
+	}
+}
+
+// buildCriteria builds the filter.Criteria along with its children and siblings.
+func (db *definitionBuilder) buildCriteria(path string, filterType string, cd *CriteriaDefinition) *filter.Criteria {
+	if cd == nil {
+		db.addProblem(path, "is empty")
+		return nil
+	}
+	c := filter.NewCriteria(
+		db.buildExtractor(path+".extractor", filterType, cd.Extractor),
+		db.buildInterpreters(path+".interpreters", filterType, cd.Interpreters)...,
+	)
+	if cd.Child != nil {
+		if child := db.buildCriteria(path+".child", filterType, cd.Child); child != nil {
+			c.AddChildren(child)
+		}
+	}
+	for i, childDefinition := range cd.Children {
+		if child := db.buildCriteria(fmt.Sprintf("%s.children[%d]", path, i), filterType, childDefinition); child != nil {
+			c.AddChildren(child)
+		}
+	}
+	for i, siblingDefinition := range cd.Siblings {
+		if sibling := db.buildCriteria(fmt.Sprintf("%s.siblings[%d]", path, i), filterType, siblingDefinition); sibling != nil {
+			c.AddSiblings(sibling)
+		}
+	}
+	return c
+}
+
+// buildInterpreters builds the filter.ConditionInterpreter instances found at the given path,
+// interpreters of the any, all and not type hold interpreters themselves which are built recursively.
+func (db *definitionBuilder) buildInterpreters(path string, filterType string, ids []*InterpreterDefinition) []filter.ConditionInterpreter {
+	if len(ids) == 0 {
+		db.addProblem(path, "at least one interpreter is required")
+	}
+	interpreters := make([]filter.ConditionInterpreter, 0)
+	for i, id := range ids {
+		path := fmt.Sprintf("%s[%d]", path, i)
+		if id == nil {
+			db.addProblem(path, "is empty")
+			continue
+		}
+		if !db.validateComponentType(path, id.Type, filterType, map[string]string{
+			HtmlTagInterpreterType:       HtmlFilterType,
+			HtmlAttributeInterpreterType: HtmlFilterType,
+			HtmlTextInterpreterType:      HtmlFilterType,
+			KeyValueInterpreterType:      JsonFilterType,
+			AnyInterpreterType:           "",
+			AllInterpreterType:           "",
+			NotInterpreterType:           "",
+		}) {
+			continue
+		}
+		switch id.Type {
+		case HtmlTagInterpreterType:
+			if id.Expr == "" {
+				db.addProblem(path+".expr", "is required")
+			} else if db.validateRegex(path+".expr", id.Expr) {
+				interpreters = append(interpreters, filter.NewHtmlTokenTagInterpreter(id.Expr))
+			}
+		case HtmlAttributeInterpreterType:
+			if db.validateRegex(path+".key", id.Key) && db.validateRegex(path+".value", id.Value) {
+				interpreters = append(interpreters, filter.NewHtmlTokenAttributeInterpreter(id.Key, id.Value))
+			}
+		case HtmlTextInterpreterType:
+			if id.Expr == "" {
+				db.addProblem(path+".expr", "is required")
+			} else if db.validateRegex(path+".expr", id.Expr) {
+				interpreters = append(interpreters, filter.NewHtmlTextInterpreter(id.Expr))
+			}
+		case KeyValueInterpreterType:
+			if db.validateRegex(path+".key", id.Key) && db.validateRegex(path+".value", id.Value) {
+				interpreters = append(interpreters, filter.NewKeyValueInterpreter(id.Key, id.Value))
+			}
+		case AnyInterpreterType:
+			interpreters = append(interpreters, filter.NewAnyInterpreter(db.buildInterpreters(path+".interpreters", filterType, id.Interpreters)...))
+		case AllInterpreterType:
+			interpreters = append(interpreters, filter.NewAllInterpreter(db.buildInterpreters(path+".interpreters", filterType, id.Interpreters)...))
+		case NotInterpreterType:
+			nested := db.buildInterpreters(path+".interpreters", filterType, id.Interpreters)
+			if len(id.Interpreters) > 1 {
+				db.addProblem(path+".interpreters", "a %s interpreter holds exactly one interpreter, use an %s interpreter to combine them", NotInterpreterType, AllInterpreterType)
+			} else if len(nested) == 1 {
+				interpreters = append(interpreters, filter.NewNotInterpreter(nested[0]))
+			}
+		}
+	}
+	return interpreters
+}
+
+func (db *definitionBuilder) buildExtractor(path string, filterType string, ed *ExtractorDefinition) filter.Extractor {
+	if ed == nil {
+		return nil
+	}
+	if !db.validateComponentType(path, ed.Type, filterType, map[string]string{
+		HtmlTextExtractorType:      HtmlFilterType,
+		HtmlAttributeExtractorType: HtmlFilterType,
+		HtmlTableExtractorType:     HtmlFilterType,
+		KeyValueExtractorType:      JsonFilterType,
+		RegexCaptureExtractorType:  "",
+	}) {
+		return nil
+	}
+	var clean []func(data map[string]any) (map[string]any, error)
+	if ed.Filter != nil {
+		clean = append(clean, newNestedFilterClean(db.buildFilter(path+".filter", ed.Filter)))
+	}
+	switch ed.Type {
+	case HtmlTextExtractorType:
+		if ed.Id == "" {
+			db.addProblem(path+".id", "is required")
+		}
+		return filter.NewHtmlTextExtractor(ed.Id, clean...)
+	case HtmlAttributeExtractorType:
+		if ed.Key == "" {
+			db.addProblem(path+".key", "is required")
+		} else if db.validateRegex(path+".key", ed.Key) {
+			return filter.NewHtmlAttributeExtractor(ed.Key, clean...)
+		}
+	case HtmlTableExtractorType:
+		return filter.NewHtmlTableExtractor(ed.Aliases, clean...)
+	case KeyValueExtractorType:
+		if db.validateRegex(path+".key", ed.Key) && db.validateRegex(path+".value", ed.Value) {
+			return filter.NewKeyValueExtractor(ed.Key, ed.Value, clean...)
+		}
+	case RegexCaptureExtractorType:
+		if ed.Expr == "" {
+			db.addProblem(path+".expr", "is required")
+		} else if db.validateRegex(path+".expr", ed.Expr) {
+			if !helper.HasNamedGroup(regexp.MustCompile(ed.Expr)) {
+				db.addProblem(path+".expr", "regex %q requires at least one named capture group, e.g. (?P<name>...)", ed.Expr)
+			} else {
+				return filter.NewRegexCaptureExtractor(ed.Expr, clean...)
+			}
+		}
+	}
+	return nil
+}
+
+// validateComponentType checks if the given component type is known and if it can be used by the given filter type,
+// components maps the known component types to the filter type they belong to, or an empty string if they fit any.
+func (db *definitionBuilder) validateComponentType(path string, componentType string, filterType string, components map[string]string) bool {
+	expectedFilterType, ok := components[componentType]
+	if !ok {
+		known := make([]string, 0)
+		for t := range components {
+			known = append(known, fmt.Sprintf("%q", t))
+		}
+		sort.Strings(known)
+		db.addProblem(path+".type", "unknown type %q, expected one of: %s", componentType, strings.Join(known, ", "))
+		return false
+	}
+	if filterType != "" && expectedFilterType != "" && expectedFilterType != filterType {
+		db.addProblem(path+".type", "type %q can not be used within a filter of type %q", componentType, filterType)
+		return false
+	}
+	return true
+}
+
+func (db *definitionBuilder) validateRegex(path string, expr string) bool {
+	if _, err := regexp.Compile(expr); err != nil {
+		db.addProblem(path, "invalid regex %q, error: %s", expr, err)
+		return false
+	}
+	return true
+}
+
+func (db *definitionBuilder) parseDuration(path string, s string) time.Duration {
+	if s == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		db.addProblem(path, "invalid duration %q, expected a format like \"90s\" or \"500ms\"", s)
+		return 0
+	}
+	return d
+}
+
+// newNestedFilterClean returns a Clean function which runs the given filter.Filter on every extracted string,
+// this allows data embedded within other data (e.g. JSON within an HTML script tag) to be filtered.
+func newNestedFilterClean(f filter.Filter) func(data map[string]any) (map[string]any, error) {
+	return func(data map[string]any) (map[string]any, error) {
+		result := make(map[string]any)
+		if f == nil {
+			return result, nil
+		}
+		for k, v := range data {
+			s, ok := v.(string)
+			if !ok {
+				logger.With("key", k).Warnf("Nested filter expected %s, got %s", reflect.TypeOf(s), reflect.TypeOf(v))
+				continue
+			}
+			nested, err := f.Clone().Filter(s)
+			if err != nil {
+				return nil, fmt.Errorf("nested filter failed for key %s, error: %w", k, err)
+			}
+			for nk, nv := range nested {
+				result[nk] = nv
+			}
+		}
+		return result, nil
+	}
+}
@@ -0,0 +1,110 @@
+package config
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// goldenDir holds a directory per scraper ID, containing captured pages and the data expected to be filtered from them.
+// Every page has a golden file next to it sharing its name, e.g. "product_page.html" and "product_page.golden.json".
+const (
+	goldenDir           = "testdata/golden"
+	goldenFileExtension = ".golden.json"
+)
+
+var update = flag.Bool("update", false, "Regenerates the golden files of all supplier filter fixtures.")
+
+// TestConfig_FilterGoldenFiles runs every registered scraper's filter over its fixtures
+// and compares the filtered data to the golden files, use -update to regenerate the golden files.
+func TestConfig_FilterGoldenFiles(t *testing.T) {
+	for _, c := range newGoConfigs() {
+		for _, s := range c.Scrapers {
+			if s.Filter == nil {
+				continue
+			}
+			fixtures, err := findGoldenFixtures(filepath.Join(goldenDir, s.GetScraperId()))
+			if err != nil {
+				t.Errorf("Failed to find fixtures for scraper %s, error: %s", s.GetScraperId(), err)
+				continue
+			}
+			if len(fixtures) == 0 {
+				t.Logf("Scraper %s of config %s has no golden file fixtures", s.GetScraperId(), c.Id)
+			}
+			for _, fixture := range fixtures {
+				t.Run(fmt.Sprintf("%s/%s", s.GetScraperId(), filepath.Base(fixture)), func(t *testing.T) {
+					page, err := os.ReadFile(fixture)
+					if err != nil {
+						t.Fatalf("Failed to read fixture %s, error: %s", fixture, err)
+					}
+					data, err := s.Filter.Clone().Filter(string(page))
+					if err != nil {
+						t.Fatalf("Failed to filter fixture %s, error: %s", fixture, err)
+					}
+					got, err := json.MarshalIndent(data, "", "  ")
+					if err != nil {
+						t.Fatalf("Failed to marshal filtered data, error: %s", err)
+					}
+					got = append(got, '\n')
+					golden := strings.TrimSuffix(fixture, filepath.Ext(fixture)) + goldenFileExtension
+					if *update {
+						if err = os.WriteFile(golden, got, 0644); err != nil {
+							t.Fatalf("Failed to update golden file %s, error: %s", golden, err)
+						}
+						return
+					}
+					expected, err := os.ReadFile(golden)
+					if err != nil {
+						t.Fatalf("Failed to read golden file %s, run go test with -update to create it, error: %s", golden, err)
+					}
+					if string(got) != string(expected) {
+						t.Errorf("Filtered data does not match golden file %s:\n%s", golden, diffLines(string(expected), string(got)))
+					}
+				})
+			}
+		}
+	}
+}
+
+// findGoldenFixtures returns all fixtures within the given directory, which is every file that is not a golden file.
+func findGoldenFixtures(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	fixtures := make([]string, 0)
+	for _, entry := range entries {
+		if !entry.IsDir() && !strings.HasSuffix(entry.Name(), goldenFileExtension) {
+			fixtures = append(fixtures, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return fixtures, nil
+}
+
+// diffLines returns the lines that differ between the expected and the actual output,
+// prefixed by "-" for expected lines and "+" for actual lines.
+func diffLines(expected string, got string) string {
+	expectedLines := strings.Split(expected, "\n")
+	gotLines := strings.Split(got, "\n")
+	var sb strings.Builder
+	for i := 0; i < len(expectedLines) || i < len(gotLines); i++ {
+		var e, g string
+		if i < len(expectedLines) {
+			e = expectedLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if e != g {
+			sb.WriteString(fmt.Sprintf("line %d:\n\t- %s\n\t+ %s\n", i+1, e, g))
+		}
+	}
+	return sb.String()
+}
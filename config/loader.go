@@ -0,0 +1,76 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// LoadConfigs reads all YAML (.yaml, .yml) and JSON (.json) files within the given directory,
+// validates their Definition and returns the resulting Config instances.
+// An error is returned describing every invalid file, or if a config ID is used more than once.
+func LoadConfigs(dir string) ([]*Config, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0)
+	for _, entry := range entries {
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".yaml", ".yml", ".json":
+			if !entry.IsDir() {
+				names = append(names, entry.Name())
+			}
+		}
+	}
+	sort.Strings(names)
+	loaded := make([]*Config, 0)
+	problems := make([]string, 0)
+	sources := make(map[string]string)
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		c, err := LoadConfig(path)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		if source, ok := sources[c.Id]; ok {
+			problems = append(problems, fmt.Sprintf("config ID %s in %s is already used by %s", c.Id, path, source))
+			continue
+		}
+		sources[c.Id] = path
+		loaded = append(loaded, c)
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("failed to load configs from %s:\n%s", dir, strings.Join(problems, "\n"))
+	}
+	return loaded, nil
+}
+
+// LoadConfig reads a single YAML or JSON file, validates its Definition and returns the resulting Config.
+// Unknown fields are rejected so typos are not silently ignored.
+func LoadConfig(path string) (*Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	d := &Definition{}
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		decoder := json.NewDecoder(bytes.NewReader(b))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(d)
+	} else {
+		decoder := yaml.NewDecoder(bytes.NewReader(b))
+		decoder.KnownFields(true)
+		err = decoder.Decode(d)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid config %s: %s", path, err)
+	}
+	return d.Build(path)
+}
@@ -0,0 +1,123 @@
+package config
+
+import (
+	"fmt"
+	"github.com/mmaaskant/gro-crop-scraper/crawler"
+	"github.com/mmaaskant/gro-crop-scraper/filter"
+	"github.com/mmaaskant/gro-crop-scraper/scraper"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// MagentoSite holds the parameters of a Magento 2 shop from which the components of its scrapers are built.
+// Categories holds the first path segment of the category pages to crawl, e.g. "vegetables", ProductPathRegex matches
+// the path of its product pages and AttributeRegex matches the keys of the product attributes to extract.
+// RestUrl is the root of its REST API, which is BaseUrl followed by "/rest/V1" if left empty.
+type MagentoSite struct {
+	BaseUrl          string
+	Categories       []string
+	ProductPathRegex string
+	AttributeRegex   string
+	RestUrl          string
+	RestToken        string
+}
+
+// NewHtmlCrawler returns a crawler.HtmlCrawler crawling the category and product pages of the shop.
+func (ms *MagentoSite) NewHtmlCrawler() *crawler.HtmlCrawler {
+	return crawler.NewMagentoHtmlCrawler(&http.Client{Timeout: 90 * time.Second}, ms.BaseUrl, ms.Categories, ms.ProductPathRegex)
+}
+
+// NewHtmlCalls returns the crawler.Call instances kicking off the crawler returned by NewHtmlCrawler.
+func (ms *MagentoSite) NewHtmlCalls() []*crawler.Call {
+	return crawler.NewMagentoHtmlCalls(ms.BaseUrl)
+}
+
+// NewHtmlFilter returns a filter.HtmlFilter extracting product attributes from the "text/x-magento-init" script
+// within the product form of a Magento 2 product page, which embeds them as JSON.
+func (ms *MagentoSite) NewHtmlFilter() *filter.HtmlFilter {
+	cb := filter.NewCriteriaBuilder(
+		filter.NewCriteria(
+			nil,
+			filter.NewHtmlTokenTagInterpreter("div"),
+			filter.NewHtmlTokenAttributeInterpreter("class", "product-add-form"),
+		),
+	)
+	cb.AddChild(filter.NewCriteria(
+		filter.NewHtmlTextExtractor("attributes", func(data map[string]any) (map[string]any, error) {
+			attributes, ok := data["attributes"].(string)
+			if !ok {
+				return nil, filter.NewTypeError(ms, attributes, data["attributes"])
+			}
+			f := filter.NewJsonFilter(
+				filter.NewCriteria(
+					filter.NewKeyValueExtractor("", ""),
+					filter.NewKeyValueInterpreter(ms.AttributeRegex, ""),
+				),
+			)
+			return f.Filter(attributes)
+		}),
+		filter.NewHtmlTokenTagInterpreter("script"),
+		filter.NewHtmlTokenAttributeInterpreter("type", "text/x-magento-init"),
+	))
+	f := filter.NewHtmlFilter(cb.Build())
+	// Increase the revision whenever the attributes Clean function changes.
+	f.SetRevision("1")
+	return f
+}
+
+// NewRestScraper returns a scraper.Scraper extracting the products of the shop from its REST API.
+func (ms *MagentoSite) NewRestScraper() *scraper.Scraper {
+	return scraper.NewScraper(ms.NewRestCrawler(), ms.NewRestCalls(), ms.NewRestFilter())
+}
+
+// NewRestCrawler returns a crawler.RestCrawler crawling the products listed by the REST API of the shop.
+func (ms *MagentoSite) NewRestCrawler() *crawler.RestCrawler {
+	return crawler.NewMagentoRestCrawler(&http.Client{Timeout: 90 * time.Second}, ms.restUrl(), ms.RestToken)
+}
+
+// NewRestCalls returns the crawler.Call instances kicking off the crawler returned by NewRestCrawler.
+func (ms *MagentoSite) NewRestCalls() []*crawler.Call {
+	return crawler.NewMagentoRestCalls(ms.restUrl())
+}
+
+// NewRestFilter returns a filter.JsonFilter extracting the product attributes matching AttributeRegex from a product
+// of the REST API, its custom attributes are stored under their attribute code so keys match those of NewHtmlFilter.
+func (ms *MagentoSite) NewRestFilter() *filter.JsonFilter {
+	attributeRegex := regexp.MustCompile(ms.AttributeRegex)
+	f := filter.NewJsonFilter(
+		filter.NewCriteria(
+			filter.NewKeyValueExtractor("", ""),
+			filter.NewKeyValueInterpreter(ms.AttributeRegex, ""),
+		),
+		filter.NewCriteria(
+			filter.NewKeyValueExtractor("", "", func(data map[string]any) (map[string]any, error) {
+				attributes := make(map[string]any)
+				items, _ := data["custom_attributes"].([]any)
+				for _, item := range items {
+					attribute, ok := item.(map[string]any)
+					if !ok {
+						continue
+					}
+					code := fmt.Sprint(attribute["attribute_code"])
+					if attributeRegex.MatchString(code) && attribute["value"] != nil {
+						attributes[code] = attribute["value"]
+					}
+				}
+				return attributes, nil
+			}),
+			filter.NewKeyValueInterpreter("^custom_attributes$", ""),
+		),
+	)
+	// Increase the revision whenever the custom attributes Clean function changes.
+	f.SetRevision("1")
+	return f
+}
+
+func (ms *MagentoSite) restUrl() string {
+	if ms.RestUrl != "" {
+		return ms.RestUrl
+	}
+	return strings.TrimRight(ms.BaseUrl, "/") + "/rest/V1"
+}
@@ -0,0 +1,139 @@
+package config
+
+import (
+	"fmt"
+	"github.com/mmaaskant/gro-crop-scraper/crawler"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestMagentoSite_Html(t *testing.T) {
+	product, err := os.ReadFile("testdata/golden/burpee_html/product_page.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`<a href="/vegetables/tomatoes">Tomatoes</a><a href="/about-us">About</a>`))
+	})
+	mux.HandleFunc("/vegetables/tomatoes", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`<a href="/vegetables/tomatoes?p=2">Next</a><a href="/sungold-tomato.html">Sungold</a>`))
+	})
+	mux.HandleFunc("/sungold-tomato.html", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(product)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	site := &MagentoSite{
+		BaseUrl:        server.URL,
+		Categories:     []string{"vegetables"},
+		AttributeRegex: "(bp_).*|name",
+	}
+	found := crawlMagentoSite(t, site.NewHtmlCrawler(), site.NewHtmlCalls())
+	expected := []string{
+		"DISCOVER " + server.URL + "/vegetables/tomatoes",
+		"DISCOVER " + server.URL + "/vegetables/tomatoes?p=2",
+		"EXTRACT " + server.URL + "/sungold-tomato.html",
+	}
+	if !reflect.DeepEqual(found.calls, expected) {
+		t.Errorf("Expected calls %v, got %v", expected, found.calls)
+	}
+	data, err := site.NewHtmlFilter().Filter(found.extracted[server.URL+"/sungold-tomato.html"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data["name"] != "Tomato, Sungold Hybrid" || data["bp_days_to_maturity"] != "57-65" || data["description"] != nil {
+		t.Errorf("Expected the attributes matching the attribute regex to be extracted, got %v", data)
+	}
+}
+
+func TestMagentoSite_Rest(t *testing.T) {
+	skus := []string{"TOM-1", "TOM/2", "COS-3"}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rest/V1/products", func(w http.ResponseWriter, r *http.Request) {
+		// Lists two products per page, any page beyond the last one repeats the last page like Magento does.
+		page, _ := strconv.Atoi(r.URL.Query().Get("searchCriteria[currentPage]"))
+		if page > 2 {
+			page = 2
+		}
+		end := page * 2
+		if end > len(skus) {
+			end = len(skus)
+		}
+		items := make([]string, 0)
+		for _, sku := range skus[(page-1)*2 : end] {
+			items = append(items, fmt.Sprintf(`{"sku": %q}`, sku))
+		}
+		_, _ = fmt.Fprintf(w, `{"items": [%s], "search_criteria": {"current_page": %d, "page_size": 2}, "total_count": %d}`,
+			strings.Join(items, ","), page, len(skus))
+	})
+	mux.HandleFunc("/rest/V1/products/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		sku := strings.TrimPrefix(r.URL.EscapedPath(), "/rest/V1/products/")
+		_, _ = fmt.Fprintf(w, `{"sku": %q, "name": "Product %s", "price": 4.99, "custom_attributes": [
+			{"attribute_code": "bp_days_to_maturity", "value": "57-65"},
+			{"attribute_code": "url_key", "value": "product"}
+		]}`, sku, sku)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	site := &MagentoSite{
+		BaseUrl:        server.URL,
+		AttributeRegex: "(bp_).*|name",
+		RestToken:      "secret",
+	}
+	s := site.NewRestScraper()
+	found := crawlMagentoSite(t, s.Crawler, s.Calls)
+	if len(found.extracted) != len(skus) {
+		t.Fatalf("Expected %d products to be extracted, got %v", len(skus), found.calls)
+	}
+	data, err := s.Filter.Filter(found.extracted[server.URL+"/rest/V1/products/TOM%2F2"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]any{"name": "Product TOM%2F2", "bp_days_to_maturity": "57-65"}
+	if !reflect.DeepEqual(data, expected) {
+		t.Errorf("Expected %v, got %v", expected, data)
+	}
+}
+
+type magentoCrawl struct {
+	calls     []string
+	extracted map[string]string
+}
+
+// crawlMagentoSite crawls all calls found by the given crawler.Crawler and returns them sorted,
+// along with the data of every extracted call mapped by its URL.
+func crawlMagentoSite(t *testing.T, cr crawler.Crawler, queue []*crawler.Call) *magentoCrawl {
+	found := &magentoCrawl{make([]string, 0), make(map[string]string)}
+	for pages := 0; len(queue) > 0; pages++ {
+		if pages > 20 {
+			t.Fatalf("Expected crawling to stop, got %v", found.calls)
+		}
+		cd := cr.Crawl(queue[0])
+		queue = queue[1:]
+		if cd.Error != nil {
+			t.Fatalf("Failed to crawl %s, error: %s", cd.Call.Request.URL, cd.Error)
+		}
+		if cd.Call.RequestType == crawler.ExtractRequestType {
+			found.extracted[cd.Call.Request.URL.String()] = cd.Data
+		}
+		for _, c := range cd.FoundCalls {
+			found.calls = append(found.calls, c.RequestType+" "+c.Request.URL.String())
+			queue = append(queue, c)
+		}
+	}
+	sort.Strings(found.calls)
+	return found
+}
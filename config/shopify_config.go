@@ -0,0 +1,210 @@
+package config
+
+import (
+	"fmt"
+	"github.com/mmaaskant/gro-crop-scraper/crawler"
+	"github.com/mmaaskant/gro-crop-scraper/filter"
+	"github.com/mmaaskant/gro-crop-scraper/scraper"
+	"golang.org/x/net/html"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ShopifyMapping maps the tags and metafields of Shopify products to the keys of model.GroCrop,
+// as every Shopify shop uses its own tags and metafields to describe its products.
+type ShopifyMapping struct {
+	tags       map[string]string
+	metafields map[string]string
+}
+
+func NewShopifyMapping() *ShopifyMapping {
+	return &ShopifyMapping{
+		make(map[string]string),
+		make(map[string]string),
+	}
+}
+
+// AddTag maps tags holding the given label to key, a tag's label is separated from its value by ":" or "_",
+// e.g. the label "Days to Maturity" maps the tag "Days to Maturity: 65" to {"days_to_maturity": "65"}.
+// Labels are compared after being normalised by filter.NormaliseLabel.
+func (m *ShopifyMapping) AddTag(label string, key string) *ShopifyMapping {
+	m.tags[filter.NormaliseLabel(label)] = key
+	return m
+}
+
+// AddMetafield maps the metafield with the given namespace and key, e.g. "seed.days_to_maturity", to key.
+func (m *ShopifyMapping) AddMetafield(name string, key string) *ShopifyMapping {
+	m.metafields[name] = key
+	return m
+}
+
+// String describes the mapping, it is part of the revision of the filter returned by NewShopifyFilter.
+func (m *ShopifyMapping) String() string {
+	pairs := make([]string, 0, len(m.tags)+len(m.metafields))
+	for label, key := range m.tags {
+		pairs = append(pairs, "tag:"+label+"="+key)
+	}
+	for name, key := range m.metafields {
+		pairs = append(pairs, "metafield:"+name+"="+key)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ", ")
+}
+
+// NewShopifyScraper returns a scraper.Scraper crawling all products of the Shopify shop at shopUrl
+// and filtering them using NewShopifyFilter, which allows a Shopify supplier to be added in a few lines:
+//
+//	m := NewShopifyMapping().AddTag("Zone", "growing_zone_range").AddMetafield("seed.days", "days_to_maturity")
+//	c.AddScraper("example_shopify", NewShopifyScraper("https://shop.example.com", m))
+func NewShopifyScraper(shopUrl string, m *ShopifyMapping) *scraper.Scraper {
+	return scraper.NewScraper(
+		crawler.NewShopifyCrawler(&http.Client{Timeout: 90 * time.Second}),
+		crawler.NewShopifyCalls(shopUrl),
+		NewShopifyFilter(m),
+	)
+}
+
+// NewShopifyFilter returns a filter.JsonFilter mapping the fields of Shopify product JSON, as served by both
+// "/products/<handle>.js" and "/products/<handle>.json", to the keys of model.GroCrop. The title is stored as "name",
+// the description as "description" without its HTML and the product type as "category". All tags are stored as "tags",
+// mapped tags and metafields are stored under the key they are mapped to.
+func NewShopifyFilter(m *ShopifyMapping) *filter.JsonFilter {
+	f := filter.NewJsonFilter(
+		filter.NewCriteria(
+			filter.NewKeyValueExtractor("", "", renameShopifyField("name", false)),
+			filter.NewKeyValueInterpreter("^title$", ""),
+		),
+		filter.NewCriteria(
+			filter.NewKeyValueExtractor("", "", renameShopifyField("description", true)),
+			filter.NewKeyValueInterpreter("^(description|body_html)$", ""),
+		),
+		filter.NewCriteria(
+			filter.NewKeyValueExtractor("", "", renameShopifyField("category", false)),
+			filter.NewKeyValueInterpreter("^(type|product_type)$", ""),
+		),
+		filter.NewCriteria(
+			filter.NewKeyValueExtractor("", "", m.mapTags),
+			filter.NewKeyValueInterpreter("^tags$", ""),
+		),
+		filter.NewCriteria(
+			filter.NewKeyValueExtractor("", "", m.mapMetafields),
+			filter.NewKeyValueInterpreter("^metafields$", ""),
+		),
+	)
+	// Increase the revision whenever any of the Clean functions change.
+	f.SetRevision("1;" + m.String())
+	return f
+}
+
+// renameShopifyField returns a Clean function storing the value of the extracted field under key,
+// if stripHtml is true the value is converted to text.
+func renameShopifyField(key string, stripHtml bool) func(data map[string]any) (map[string]any, error) {
+	return func(data map[string]any) (map[string]any, error) {
+		for _, v := range data {
+			s, ok := v.(string)
+			if !ok || s == "" {
+				return nil, nil
+			}
+			if stripHtml {
+				s = htmlText(s)
+			}
+			return map[string]any{key: s}, nil
+		}
+		return nil, nil
+	}
+}
+
+// mapTags is a Clean function storing all tags as "tags" and the values of mapped tags under their key,
+// tags are either a list or a comma separated string. A key that is mapped more than once holds a list of values.
+func (m *ShopifyMapping) mapTags(data map[string]any) (map[string]any, error) {
+	tags := make([]string, 0)
+	switch t := data["tags"].(type) {
+	case string:
+		for _, tag := range strings.Split(t, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+	case []any:
+		for _, tag := range t {
+			tags = append(tags, strings.TrimSpace(fmt.Sprint(tag)))
+		}
+	default:
+		return nil, nil
+	}
+	mapped := map[string]any{"tags": tags}
+	for _, tag := range tags {
+		i := strings.IndexAny(tag, ":_")
+		if i < 0 {
+			continue
+		}
+		if key, ok := m.tags[filter.NormaliseLabel(tag[:i])]; ok {
+			addShopifyValue(mapped, key, strings.TrimSpace(tag[i+1:]))
+		}
+	}
+	return mapped, nil
+}
+
+// mapMetafields is a Clean function storing the values of mapped metafields under their key, metafields are either
+// a list of objects holding a namespace, key and value or an object holding an object of keys and values per namespace.
+func (m *ShopifyMapping) mapMetafields(data map[string]any) (map[string]any, error) {
+	mapped := make(map[string]any)
+	switch t := data["metafields"].(type) {
+	case []any:
+		for _, item := range t {
+			if field, ok := item.(map[string]any); ok {
+				m.addMetafield(mapped, fmt.Sprintf("%v.%v", field["namespace"], field["key"]), field["value"])
+			}
+		}
+	case map[string]any:
+		for namespace, fields := range t {
+			if fields, ok := fields.(map[string]any); ok {
+				for key, value := range fields {
+					m.addMetafield(mapped, namespace+"."+key, value)
+				}
+			}
+		}
+	}
+	if len(mapped) == 0 {
+		return nil, nil
+	}
+	return mapped, nil
+}
+
+func (m *ShopifyMapping) addMetafield(mapped map[string]any, name string, value any) {
+	if key, ok := m.metafields[name]; ok && value != nil {
+		addShopifyValue(mapped, key, strings.TrimSpace(fmt.Sprint(value)))
+	}
+}
+
+// addShopifyValue stores the value under key, a key that is stored more than once holds a list of values.
+func addShopifyValue(mapped map[string]any, key string, value string) {
+	if value == "" {
+		return
+	}
+	switch existing := mapped[key].(type) {
+	case nil:
+		mapped[key] = value
+	case string:
+		mapped[key] = []string{existing, value}
+	case []string:
+		mapped[key] = append(existing, value)
+	}
+}
+
+// htmlText returns the text held by the given HTML with collapsed whitespace.
+func htmlText(s string) string {
+	var sb strings.Builder
+	z := html.NewTokenizer(strings.NewReader(s))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return strings.Join(strings.Fields(sb.String()), " ")
+		case html.TextToken:
+			sb.Write(z.Text())
+			sb.WriteString(" ")
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/mmaaskant/gro-crop-scraper/crawler"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// shopifyProducts holds the Shopify-shaped product JSON served by the stand-in shop, the first product as served
+// by "/products/<handle>.js" and the second as served by "/products/<handle>.json".
+var shopifyProducts = map[string]string{
+	"sungold-tomato": `{
+		"id": 7012345678901,
+		"title": "Tomato, Sungold Hybrid",
+		"handle": "sungold-tomato",
+		"description": "<p>Super-sweet, <strong>golden</strong> cherry tomatoes.</p>",
+		"type": "Vegetable Seeds",
+		"vendor": "Example Seeds",
+		"tags": ["Organic", "Days to Maturity: 57", "Zone_3", "Zone_4", "sun:Full Sun"],
+		"variants": [{"id": 1, "title": "Packet", "price": 499}]
+	}`,
+	"cosmos-sensation": `{
+		"product": {
+			"id": 7012345678902,
+			"title": "Cosmos, Sensation Mix",
+			"handle": "cosmos-sensation",
+			"body_html": "Tall, airy flowers.",
+			"product_type": "Flower Seeds",
+			"tags": "Annual, Days to Maturity: 80",
+			"metafields": [
+				{"namespace": "seed", "key": "height", "value": "48\""},
+				{"namespace": "seed", "key": "unmapped", "value": "ignored"}
+			]
+		}
+	}`,
+}
+
+func TestShopifyScraper(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/products.json", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("limit") != fmt.Sprint(crawler.ShopifyPageSize) || r.URL.Query().Get("page") == "2" {
+			_, _ = w.Write([]byte(`{"products": []}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"products": [{"handle": "sungold-tomato"}, {"handle": "cosmos-sensation"}]}`))
+	})
+	for handle, product := range shopifyProducts {
+		product := product
+		mux.HandleFunc("/products/"+handle+".js", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(product))
+		})
+	}
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	m := NewShopifyMapping().
+		AddTag("days to maturity", "days_to_maturity").
+		AddTag("Zone", "growing_zone_range").
+		AddTag("Sun", "sun_requirement").
+		AddMetafield("seed.height", "mature_height")
+	s := NewShopifyScraper(server.URL, m)
+	s.Crawler.(*crawler.RestCrawler).SetRateLimit(0)
+
+	extracted := make(map[string]map[string]any)
+	queue := s.Calls
+	for len(queue) > 0 {
+		cd := s.Crawler.Crawl(queue[0])
+		queue = append(queue[1:], cd.FoundCalls...)
+		if cd.Error != nil {
+			t.Fatalf("Failed to crawl %s, error: %s", cd.Call.Request.URL, cd.Error)
+		}
+		if cd.Call.RequestType != crawler.ExtractRequestType {
+			continue
+		}
+		data, err := s.Filter.Clone().Filter(cd.Data)
+		if err != nil {
+			t.Fatalf("Failed to filter %s, error: %s", cd.Call.Request.URL, err)
+		}
+		extracted[cd.Call.Request.URL.Path] = data
+	}
+	expected := map[string]map[string]any{
+		"/products/sungold-tomato.js": {
+			"name":               "Tomato, Sungold Hybrid",
+			"description":        "Super-sweet, golden cherry tomatoes.",
+			"category":           "Vegetable Seeds",
+			"tags":               []string{"Organic", "Days to Maturity: 57", "Zone_3", "Zone_4", "sun:Full Sun"},
+			"days_to_maturity":   "57",
+			"growing_zone_range": []string{"3", "4"},
+			"sun_requirement":    "Full Sun",
+		},
+		"/products/cosmos-sensation.js": {
+			"name":             "Cosmos, Sensation Mix",
+			"description":      "Tall, airy flowers.",
+			"category":         "Flower Seeds",
+			"tags":             []string{"Annual", "Days to Maturity: 80"},
+			"days_to_maturity": "80",
+			"mature_height":    "48\"",
+		},
+	}
+	if !reflect.DeepEqual(extracted, expected) {
+		got, _ := json.MarshalIndent(extracted, "", "  ")
+		t.Errorf("Extracted data does not match, got:\n%s", got)
+	}
+}
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"fmt"
+	"github.com/mmaaskant/gro-crop-scraper/crawler"
+	"github.com/mmaaskant/gro-crop-scraper/filter"
+	"strings"
+)
+
+const (
+	CrawlMethodStepId   string = crawler.StepId
+	FilterMethodStepId  string = filter.StepId
+	MapMethodStepId     string = "map"
+	CompileMethodStepId string = "compile"
+)
+
+// StepIds holds all steps in the order they are run in.
+var StepIds = []string{CrawlMethodStepId, FilterMethodStepId, MapMethodStepId, CompileMethodStepId}
+
+// ParseSteps parses a comma separated list of steps, e.g. "crawl,filter", an empty list selects all steps.
+func ParseSteps(s string) ([]string, error) {
+	if strings.TrimSpace(s) == "" {
+		return StepIds, nil
+	}
+	steps := make([]string, 0)
+	for _, step := range strings.Split(s, ",") {
+		step = strings.TrimSpace(step)
+		if !isStep(step) {
+			return nil, fmt.Errorf("unknown step %q, expected one of %s", step, strings.Join(StepIds, ", "))
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+func isStep(step string) bool {
+	for _, id := range StepIds {
+		if id == step {
+			return true
+		}
+	}
+	return false
+}
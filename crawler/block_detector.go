@@ -0,0 +1,169 @@
+package crawler
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultCoolDown is how long requests to a host are paused after it first blocked a request.
+	DefaultCoolDown = 30 * time.Second
+	// DefaultMaxCoolDown is the longest requests to a host are paused, as the cool-down doubles for every block in a row.
+	DefaultMaxCoolDown = 10 * time.Minute
+)
+
+// BlockDetector classifies a response as blocked, e.g. an "Access denied" or captcha page served with status 200,
+// so it is not stored as crawled data.
+type BlockDetector interface {
+	// Blocked returns the reason the response to the given Call shows the client was blocked,
+	// or an empty string if it was not blocked.
+	Blocked(c *Call, resp *http.Response, body string) string
+}
+
+// BlockedError is returned for a Call whose response was classified as blocked by a BlockDetector.
+type BlockedError struct {
+	Reason   string
+	CoolDown time.Duration
+}
+
+func (be *BlockedError) Error() string {
+	return fmt.Sprintf("blocked: %s, cooling down host for %s", be.Reason, be.CoolDown)
+}
+
+// StatusBlockDetector classifies responses having one of its status codes as blocked, e.g. 403 or 429.
+type StatusBlockDetector struct {
+	codes []int
+}
+
+func NewStatusBlockDetector(codes ...int) *StatusBlockDetector {
+	return &StatusBlockDetector{
+		codes,
+	}
+}
+
+// Blocked implements BlockDetector.Blocked.
+func (sd *StatusBlockDetector) Blocked(c *Call, resp *http.Response, body string) string {
+	for _, code := range sd.codes {
+		if resp != nil && resp.StatusCode == code {
+			return fmt.Sprintf("status %d", code)
+		}
+	}
+	return ""
+}
+
+// RegexBlockDetector classifies responses whose body matches its regex as blocked, e.g. "(?i)access denied".
+type RegexBlockDetector struct {
+	regex *regexp.Regexp
+}
+
+func NewRegexBlockDetector(expr string) (*RegexBlockDetector, error) {
+	r, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	return &RegexBlockDetector{
+		r,
+	}, nil
+}
+
+// Blocked implements BlockDetector.Blocked.
+func (rd *RegexBlockDetector) Blocked(c *Call, resp *http.Response, body string) string {
+	if rd.regex.MatchString(body) {
+		return fmt.Sprintf("body matches %q", rd.regex.String())
+	}
+	return ""
+}
+
+// MarkerBlockDetector classifies responses to ExtractRequestType calls as blocked if their body does not match
+// its regex, which holds a marker every page to be extracted contains, e.g. the class of a product form.
+type MarkerBlockDetector struct {
+	regex *regexp.Regexp
+}
+
+func NewMarkerBlockDetector(expr string) (*MarkerBlockDetector, error) {
+	r, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	return &MarkerBlockDetector{
+		r,
+	}, nil
+}
+
+// Blocked implements BlockDetector.Blocked.
+func (md *MarkerBlockDetector) Blocked(c *Call, resp *http.Response, body string) string {
+	if c.RequestType == ExtractRequestType && !md.regex.MatchString(body) {
+		return fmt.Sprintf("body is missing marker %q", md.regex.String())
+	}
+	return ""
+}
+
+// coolDowns pauses requests to hosts that blocked a request, the cool-down of a host doubles for every block in a row
+// up to max, and is reset once a request to the host is no longer blocked.
+type coolDowns struct {
+	base  time.Duration
+	max   time.Duration
+	hosts map[string]*coolDown
+	mutex sync.Mutex
+}
+
+type coolDown struct {
+	until  time.Time
+	blocks int
+}
+
+func newCoolDowns(base time.Duration, max time.Duration) *coolDowns {
+	return &coolDowns{
+		base,
+		max,
+		make(map[string]*coolDown),
+		sync.Mutex{},
+	}
+}
+
+// wait blocks until the given host is no longer cooling down.
+func (cds *coolDowns) wait(host string) {
+	for {
+		cds.mutex.Lock()
+		var delay time.Duration
+		if cd, ok := cds.hosts[host]; ok {
+			delay = time.Until(cd.until)
+		}
+		cds.mutex.Unlock()
+		if delay <= 0 {
+			return
+		}
+		time.Sleep(delay)
+	}
+}
+
+// block starts or extends the cool-down of the given host and returns its duration.
+func (cds *coolDowns) block(host string) time.Duration {
+	cds.mutex.Lock()
+	defer cds.mutex.Unlock()
+	cd, ok := cds.hosts[host]
+	if !ok {
+		cd = &coolDown{}
+		cds.hosts[host] = cd
+	}
+	d := cds.base
+	for i := 0; i < cd.blocks && d < cds.max; i++ {
+		d *= 2
+	}
+	if d > cds.max {
+		d = cds.max
+	}
+	cd.blocks++
+	cd.until = time.Now().Add(d)
+	return d
+}
+
+// reset resets the cool-down of the given host.
+func (cds *coolDowns) reset(host string) {
+	cds.mutex.Lock()
+	defer cds.mutex.Unlock()
+	delete(cds.hosts, host)
+}
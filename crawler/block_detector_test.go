@@ -0,0 +1,64 @@
+package crawler
+
+import (
+	"bytes"
+	"errors"
+	"github.com/mmaaskant/gro-crop-scraper/logger"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBlockDetector_Crawl(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/captcha", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("<title>Please solve this captcha</title>"))
+	})
+	mux.HandleFunc("/category", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("<a href=\"/product\">product</a>"))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	rc := NewRestCrawler(&http.Client{})
+	rd, _ := NewRegexBlockDetector(`(?i)captcha`)
+	rc.AddBlockDetector(rd)
+	md, _ := NewMarkerBlockDetector(`product-add-form`)
+	rc.AddBlockDetector(md)
+	rc.SetCoolDown(50*time.Millisecond, 80*time.Millisecond)
+
+	// The warning is logged by the logger carried by the request, which Manager sets to hold the run and config.
+	buf := new(bytes.Buffer)
+	logger.SetOutput(buf)
+	defer logger.SetOutput(os.Stderr)
+	req := NewRequest(http.MethodGet, server.URL+"/captcha", nil)
+	req = req.WithContext(logger.NewContext(req.Context(), logger.With("run_id", "run-1", "config_id", "test")))
+	var be *BlockedError
+	cd := rc.Crawl(NewCall(req, DiscoverRequestType))
+	if !errors.As(cd.Error, &be) || be.CoolDown != 50*time.Millisecond {
+		t.Fatalf("Expected the captcha page to be blocked with a cool-down of 50ms, got %v", cd.Error)
+	}
+	if !strings.Contains(buf.String(), "run-1") || !strings.Contains(buf.String(), "captcha") {
+		t.Errorf("Expected the blocked request to be logged along with its run and reason, got %q", buf.String())
+	}
+	start := time.Now()
+	cd = rc.Crawl(NewCall(NewRequest(http.MethodGet, server.URL+"/captcha", nil), DiscoverRequestType))
+	if !errors.As(cd.Error, &be) || be.CoolDown != 80*time.Millisecond {
+		t.Fatalf("Expected the cool-down to double up to 80ms, got %v", cd.Error)
+	}
+	if time.Since(start) < 40*time.Millisecond {
+		t.Errorf("Expected the request to wait for the host to cool down")
+	}
+
+	time.Sleep(80 * time.Millisecond)
+	if cd = rc.Crawl(NewCall(NewRequest(http.MethodGet, server.URL+"/category", nil), DiscoverRequestType)); cd.Error != nil {
+		t.Errorf("Expected the marker to only be required for pages to be extracted, got %v", cd.Error)
+	}
+	cd = rc.Crawl(NewCall(NewRequest(http.MethodGet, server.URL+"/category", nil), ExtractRequestType))
+	if !errors.As(cd.Error, &be) || be.CoolDown != 50*time.Millisecond {
+		t.Errorf("Expected a page to be extracted without marker to be blocked after the cool-down was reset, got %v", cd.Error)
+	}
+}
@@ -0,0 +1,129 @@
+package crawler
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"github.com/andybalholm/brotli"
+	"golang.org/x/net/html/charset"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+const (
+	// DefaultMaxBodySize is the largest response body in bytes a Crawler reads, larger responses fail to be crawled.
+	DefaultMaxBodySize = 10 << 20
+	// AcceptEncoding lists the content encodings ReadBody is able to decode, it is sent along with every request.
+	AcceptEncoding = "gzip, deflate, br"
+)
+
+// BodyTooLargeError is returned for a response whose body exceeds the maximum body size.
+type BodyTooLargeError struct {
+	MaxSize int64
+}
+
+func (be *BodyTooLargeError) Error() string {
+	return fmt.Sprintf("response body exceeds the maximum size of %d bytes", be.MaxSize)
+}
+
+// ReadBody reads the body of the given http.Response and returns it as UTF-8. The body is decompressed according
+// to its Content-Encoding header and decoded from the charset found within its BOM, its Content-Type header or its
+// HTML meta tags, falling back to Windows-1252 if it is not valid UTF-8. The charset of the Content-Type header is
+// set to utf-8 as the body no longer holds its original charset once read. A maxSize above 0 limits the amount
+// of decompressed bytes read, a BodyTooLargeError is returned if the body exceeds it.
+func ReadBody(resp *http.Response, maxSize int64) (string, error) {
+	if maxSize > 0 && resp.ContentLength > maxSize && resp.Header.Get("Content-Encoding") == "" {
+		return "", &BodyTooLargeError{maxSize}
+	}
+	r, err := decompress(resp)
+	if err != nil {
+		return "", err
+	}
+	if maxSize > 0 {
+		r = io.LimitReader(r, maxSize+1)
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	if maxSize > 0 && int64(len(b)) > maxSize {
+		return "", &BodyTooLargeError{maxSize}
+	}
+	body, err := decodeCharset(b, resp.Header.Get("Content-Type"))
+	if err != nil {
+		return "", err
+	}
+	setUtf8Charset(resp.Header)
+	return body, nil
+}
+
+// decompress returns a reader decoding the body of the given http.Response according to its Content-Encoding header,
+// the header is removed as the body no longer holds the encoding once read.
+func decompress(resp *http.Response) (io.Reader, error) {
+	encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+	var r io.Reader
+	switch encoding {
+	case "", "identity":
+		return resp.Body, nil
+	case "gzip", "x-gzip":
+		gr, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress gzip body, error: %w", err)
+		}
+		r = gr
+	case "deflate":
+		r = newDeflateReader(resp.Body)
+	case "br":
+		r = brotli.NewReader(resp.Body)
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+	}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return r, nil
+}
+
+// newDeflateReader returns a reader decoding a deflate body, which should be zlib wrapped
+// but is sent as raw deflate data by some servers.
+func newDeflateReader(r io.Reader) io.Reader {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(2)
+	// A zlib header uses the deflate compression method and is a multiple of 31.
+	if err == nil && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		if zr, err := zlib.NewReader(br); err == nil {
+			return zr
+		}
+	}
+	return flate.NewReader(br)
+}
+
+// setUtf8Charset sets the charset of the given Content-Type header to utf-8, a header that can not be parsed is left as is.
+func setUtf8Charset(header http.Header) {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		return
+	}
+	params["charset"] = "utf-8"
+	header.Set("Content-Type", mime.FormatMediaType(mediaType, params))
+}
+
+// decodeCharset converts the given body to UTF-8 from the charset determined by its BOM, the given Content-Type
+// or its HTML meta tags. Invalid UTF-8 sequences are replaced, so the body can always be stored.
+func decodeCharset(b []byte, contentType string) (string, error) {
+	e, name, _ := charset.DetermineEncoding(b, contentType)
+	if name != "utf-8" {
+		decoded, err := e.NewDecoder().Bytes(b)
+		if err != nil {
+			return "", fmt.Errorf("failed to decode body from charset %s, error: %w", name, err)
+		}
+		b = decoded
+	}
+	return strings.ToValidUTF8(string(bytes.TrimPrefix(b, []byte("\xef\xbb\xbf"))), "\uFFFD"), nil
+}
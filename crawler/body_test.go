@@ -0,0 +1,88 @@
+package crawler
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"github.com/andybalholm/brotli"
+	"io"
+	"mime"
+	"net/http"
+	"testing"
+)
+
+func TestReadBody(t *testing.T) {
+	name := "Tomaat 'Cœur de Bœuf'"
+	// "Cœur de Bœuf" encoded as Windows-1252, in which œ is 0x9c.
+	windows1252 := []byte("Tomaat 'C\x9cur de B\x9cuf'")
+	tests := []struct {
+		name        string
+		contentType string
+		encoding    string
+		body        []byte
+		maxSize     int64
+		expected    string
+		tooLarge    bool
+	}{
+		{"utf-8", "text/html; charset=utf-8", "", []byte(name), 0, name, false},
+		{"utf-8 BOM", "application/json", "", append([]byte("\xef\xbb\xbf"), name...), 0, name, false},
+		{"header charset", "text/html; charset=windows-1252", "", windows1252, 0, name, false},
+		{"meta charset", "text/html", "", append([]byte(`<meta charset="windows-1252">`), windows1252...), 0, `<meta charset="windows-1252">` + name, false},
+		{"invalid utf-8", "application/json", "", windows1252, 0, name, false},
+		{"utf-16 BOM", "text/plain", "", []byte("\xff\xfeo\x00k\x00"), 0, "ok", false},
+		{"gzip", "text/plain", "gzip", compress(t, "gzip", name), 0, name, false},
+		{"zlib deflate", "text/plain", "deflate", compress(t, "zlib", name), 0, name, false},
+		{"raw deflate", "text/plain", "deflate", compress(t, "deflate", name), 0, name, false},
+		{"brotli", "text/plain", "br", compress(t, "br", name), 0, name, false},
+		{"too large", "text/plain", "", []byte(name), 10, "", true},
+		{"too large decompressed", "text/plain", "gzip", compress(t, "gzip", name), 10, "", true},
+		{"max size", "text/plain", "", []byte(name), int64(len(name)), name, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{
+				Header:        http.Header{"Content-Type": {tt.contentType}},
+				Body:          io.NopCloser(bytes.NewReader(tt.body)),
+				ContentLength: int64(len(tt.body)),
+			}
+			if tt.encoding != "" {
+				resp.Header.Set("Content-Encoding", tt.encoding)
+			}
+			body, err := ReadBody(resp, tt.maxSize)
+			var be *BodyTooLargeError
+			if tt.tooLarge != errors.As(err, &be) {
+				t.Fatalf("Expected body too large to be %t, got error %v", tt.tooLarge, err)
+			}
+			if !tt.tooLarge && (err != nil || body != tt.expected) {
+				t.Errorf("Expected %q, got %q and error %v", tt.expected, body, err)
+			}
+			if _, params, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); !tt.tooLarge && params["charset"] != "utf-8" {
+				t.Errorf("Expected the Content-Type charset to be utf-8, got %q", resp.Header.Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func compress(t *testing.T, format string, s string) []byte {
+	buf := new(bytes.Buffer)
+	var w io.WriteCloser
+	switch format {
+	case "gzip":
+		w = gzip.NewWriter(buf)
+	case "zlib":
+		w = zlib.NewWriter(buf)
+	case "deflate":
+		w, _ = flate.NewWriter(buf, flate.DefaultCompression)
+	case "br":
+		w = brotli.NewWriter(buf)
+	}
+	if _, err := w.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
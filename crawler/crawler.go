@@ -2,9 +2,11 @@ package crawler
 
 import (
 	"github.com/mmaaskant/gro-crop-scraper/attribute"
+	"github.com/mmaaskant/gro-crop-scraper/logger"
 	"io"
-	"log"
 	"net/http"
+	"sync"
+	"time"
 )
 
 const (
@@ -19,6 +21,12 @@ type Crawler interface { // TODO: Clients should support retries
 	Crawl(c *Call) *Data
 }
 
+// UrlRegistry is implemented by a Crawler that keeps a registry of the URLs it has found,
+// the registry is reset before every crawl so URLs found by an earlier crawl in the same process are crawled again.
+type UrlRegistry interface {
+	ResetUrlRegistry()
+}
+
 // Call wraps around a http.Request and adds a RequestType which should be either DiscoverRequestType or ExtractRequestType.
 // In which the former will be used only to discover new URLs, and the latter will be stored locally for further processing.
 type Call struct {
@@ -36,26 +44,62 @@ func NewCall(r *http.Request, RequestType string) *Call {
 func NewRequest(method string, url string, body io.Reader) *http.Request {
 	r, err := http.NewRequest(method, url, body)
 	if err != nil {
-		log.Panicf("Failed to create crawler HTTP request, error: %s", err)
+		logger.With("url", url).Panicf("Failed to create crawler HTTP request, error: %s", err)
 	}
 	return r
 }
 
 // Data contains all data that was found by a Crawler.Crawl call, the Call itself, and a collection of found calls.
+// Response holds the metadata of the received http.Response, its body has already been read into Data.
 type Data struct {
 	*attribute.Tag
 	Call       *Call
+	Response   *http.Response
 	Data       string
 	FoundCalls []*Call
 	Error      error
 }
 
-func NewData(t *attribute.Tag, call *Call, data string, foundCalls []*Call, err error) *Data {
+func NewData(t *attribute.Tag, call *Call, resp *http.Response, data string, foundCalls []*Call, err error) *Data {
 	return &Data{
 		t,
 		call,
+		resp,
 		data,
 		foundCalls,
 		err,
 	}
 }
+
+// rateLimiter spaces out requests made by a Crawler so that at most one request is made per interval.
+// A nil rateLimiter does not limit anything.
+type rateLimiter struct {
+	interval time.Duration
+	next     time.Time
+	mutex    sync.Mutex
+}
+
+func newRateLimiter(interval time.Duration) *rateLimiter {
+	if interval <= 0 {
+		return nil
+	}
+	return &rateLimiter{
+		interval: interval,
+	}
+}
+
+// wait blocks until the next request is allowed to be made.
+func (rl *rateLimiter) wait() {
+	if rl == nil {
+		return
+	}
+	rl.mutex.Lock()
+	now := time.Now()
+	if rl.next.Before(now) {
+		rl.next = now
+	}
+	delay := rl.next.Sub(now)
+	rl.next = rl.next.Add(rl.interval)
+	rl.mutex.Unlock()
+	time.Sleep(delay)
+}
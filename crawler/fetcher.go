@@ -0,0 +1,196 @@
+package crawler
+
+import (
+	"fmt"
+	"github.com/mmaaskant/gro-crop-scraper/logger"
+	"github.com/mmaaskant/gro-crop-scraper/metrics"
+	"net/http"
+	"net/http/cookiejar"
+	"sync"
+	"time"
+)
+
+// DefaultUserAgent is sent along with every request unless a Crawler sets its own User-Agent header.
+const DefaultUserAgent = "Mozilla/5.0 (compatible; GroCropScraper/1.0; +https://github.com/mmaaskant/gro-crop-scraper)"
+
+// fetcher makes the requests of a Crawler, it adds the Crawler's default headers, applies its rate limit
+// and starts its Session before the first request is made and again once it has expired.
+// Responses classified as blocked by any of its BlockDetector instances pause requests to their host for a while.
+// Its methods are promoted by the Crawler instances embedding it.
+type fetcher struct {
+	client      *http.Client
+	header      http.Header
+	limiter     *rateLimiter
+	session     Session
+	started     bool
+	generation  int
+	detectors   []BlockDetector
+	coolDowns   *coolDowns
+	maxBodySize int64
+	mutex       sync.Mutex
+}
+
+// newFetcher returns a fetcher using the given http.Client, a cookie jar is added to the http.Client if it has none
+// so cookies set by the Crawler's Session or any response are sent along with subsequent requests.
+func newFetcher(c *http.Client) *fetcher {
+	if c.Jar == nil {
+		jar, _ := cookiejar.New(nil)
+		c.Jar = jar
+	}
+	header := make(http.Header)
+	header.Set("User-Agent", DefaultUserAgent)
+	header.Set("Accept-Encoding", AcceptEncoding)
+	return &fetcher{
+		c,
+		header,
+		nil,
+		nil,
+		false,
+		0,
+		make([]BlockDetector, 0),
+		newCoolDowns(DefaultCoolDown, DefaultMaxCoolDown),
+		DefaultMaxBodySize,
+		sync.Mutex{},
+	}
+}
+
+// SetRateLimit limits the Crawler to a single request per interval, an interval of 0 disables the limit.
+func (f *fetcher) SetRateLimit(interval time.Duration) {
+	f.limiter = newRateLimiter(interval)
+}
+
+// SetHeader sets a header that is sent along with every request, unless the request sets the header itself.
+func (f *fetcher) SetHeader(key string, value string) {
+	f.header.Set(key, value)
+}
+
+// SetSession sets the Session that is started before the Crawler makes its first request.
+func (f *fetcher) SetSession(s Session) {
+	f.session = s
+}
+
+// SetMaxBodySize sets the largest response body in bytes that is read, a size of 0 disables the limit.
+func (f *fetcher) SetMaxBodySize(size int64) {
+	f.maxBodySize = size
+}
+
+// AddBlockDetector adds a BlockDetector, any response it classifies as blocked is returned along with a BlockedError.
+func (f *fetcher) AddBlockDetector(d BlockDetector) {
+	f.detectors = append(f.detectors, d)
+}
+
+// SetCoolDown sets how long requests to a host are paused after it blocked a request, the cool-down doubles for every
+// blocked request in a row up to max.
+func (f *fetcher) SetCoolDown(base time.Duration, max time.Duration) {
+	f.coolDowns = newCoolDowns(base, max)
+}
+
+// do calls the http.Request of the provided Call and returns its body along with the http.Response it was read from,
+// once its host is no longer cooling down. If the response is classified as blocked, a BlockedError is returned
+// and its host cools down.
+func (f *fetcher) do(scraperId string, c *Call) (string, *http.Response, error) {
+	f.coolDowns.wait(c.Request.URL.Host)
+	body, resp, err := f.doWithSession(scraperId, c.Request)
+	if err != nil {
+		return body, resp, err
+	}
+	for _, d := range f.detectors {
+		if reason := d.Blocked(c, resp, body); reason != "" {
+			coolDown := f.coolDowns.block(c.Request.URL.Host)
+			requestLogger(scraperId, c.Request).With("reason", reason).
+				Warnf("Request was blocked, cooling down host for %s", coolDown)
+			return body, resp, &BlockedError{reason, coolDown}
+		}
+	}
+	f.coolDowns.reset(c.Request.URL.Host)
+	return body, resp, nil
+}
+
+// doWithSession calls the provided http.Request and returns its body along with the http.Response it was read from.
+// If the response shows the Session has expired, the Session is started again and the request is retried once.
+func (f *fetcher) doWithSession(scraperId string, req *http.Request) (string, *http.Response, error) {
+	generation, err := f.startSession(scraperId, req, -1)
+	if err != nil {
+		return "", nil, err
+	}
+	body, resp, err := f.fetch(scraperId, req)
+	if err != nil || f.session == nil || !f.session.Expired(resp, body) {
+		return body, resp, err
+	}
+	requestLogger(scraperId, req).Infof("Session has expired, starting a new session")
+	if _, err = f.startSession(scraperId, req, generation); err != nil {
+		return "", resp, err
+	}
+	if req.Body != nil {
+		if req.GetBody == nil {
+			return body, resp, fmt.Errorf("session expired, the request can not be retried as its body can not be read again")
+		}
+		if req.Body, err = req.GetBody(); err != nil {
+			return "", resp, err
+		}
+	}
+	body, resp, err = f.fetch(scraperId, req)
+	if err == nil && f.session.Expired(resp, body) {
+		return body, resp, fmt.Errorf("session expired again right after it was started")
+	}
+	return body, resp, err
+}
+
+// startSession starts the Session if it has not been started yet, or if expired is the generation of the Session
+// that has expired, and returns the current generation. Concurrent calls for an expired Session only start it once.
+func (f *fetcher) startSession(scraperId string, req *http.Request, expired int) (int, error) {
+	if f.session == nil {
+		return 0, nil
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.started && f.generation != expired {
+		return f.generation, nil
+	}
+	f.started = false
+	if err := f.session.Start(f.send); err != nil {
+		return f.generation, fmt.Errorf("failed to start session, error: %w", err)
+	}
+	f.started = true
+	f.generation++
+	requestLogger(scraperId, req).Debugf("Started session")
+	return f.generation, nil
+}
+
+// requestLogger returns the logger.Logger held by the context of the given http.Request, which Manager sets to hold
+// the run, config, scraper and URL of the call, or a logger.Logger holding the scraper ID and URL if it holds none.
+func requestLogger(scraperId string, req *http.Request) *logger.Logger {
+	if l, ok := logger.FromContext(req.Context()); ok {
+		return l
+	}
+	return logger.With("scraper_id", scraperId, "url", req.URL.String())
+}
+
+// fetch calls the http.Request using send and reads its body using ReadBody.
+func (f *fetcher) fetch(scraperId string, req *http.Request) (string, *http.Response, error) {
+	start := time.Now()
+	resp, err := f.send(req)
+	if err != nil {
+		metrics.ObserveFetch(scraperId, req, nil, start)
+		return "", nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ReadBody(resp, f.maxBodySize)
+	metrics.ObserveFetch(scraperId, req, resp, start)
+	if err != nil {
+		return "", resp, err
+	}
+	return body, resp, err
+}
+
+// send adds the default headers to the http.Request and calls it once the rate limit allows it,
+// it is used for the requests of the Crawler as well as those of its Session.
+func (f *fetcher) send(req *http.Request) (*http.Response, error) {
+	for key, values := range f.header {
+		if req.Header.Get(key) == "" {
+			req.Header[key] = values
+		}
+	}
+	f.limiter.wait()
+	return f.client.Do(req)
+}
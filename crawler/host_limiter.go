@@ -0,0 +1,37 @@
+package crawler
+
+import "sync"
+
+// hostLimiter limits the amount of calls that are crawled at the same time per host,
+// a limit of 0 does not limit calls beyond the amount of workers crawling them.
+type hostLimiter struct {
+	limit int
+	hosts map[string]chan struct{}
+	mutex sync.Mutex
+}
+
+func newHostLimiter(limit int) *hostLimiter {
+	return &hostLimiter{
+		limit,
+		make(map[string]chan struct{}),
+		sync.Mutex{},
+	}
+}
+
+// acquire blocks until a call to the given host may be crawled, the returned function must be called once it has been.
+func (hl *hostLimiter) acquire(host string) func() {
+	if hl == nil || hl.limit <= 0 {
+		return func() {}
+	}
+	hl.mutex.Lock()
+	semaphore, ok := hl.hosts[host]
+	if !ok {
+		semaphore = make(chan struct{}, hl.limit)
+		hl.hosts[host] = semaphore
+	}
+	hl.mutex.Unlock()
+	semaphore <- struct{}{}
+	return func() {
+		<-semaphore
+	}
+}
@@ -0,0 +1,45 @@
+package crawler
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHostLimiter_Acquire(t *testing.T) {
+	hl := newHostLimiter(2)
+	var running, maxRunning, otherHost int32
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer hl.acquire("www.burpee.com")()
+			n := atomic.AddInt32(&running, 1)
+			for {
+				m := atomic.LoadInt32(&maxRunning)
+				if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer hl.acquire("www.example.com")()
+		atomic.StoreInt32(&otherHost, 1)
+	}()
+	wg.Wait()
+	if maxRunning != 2 {
+		t.Errorf("Expected at most 2 calls per host to be crawled at the same time, got %d", maxRunning)
+	}
+	if otherHost != 1 {
+		t.Errorf("Expected calls to another host to be crawled")
+	}
+	var unlimited *hostLimiter
+	unlimited.acquire("www.burpee.com")()
+}
@@ -4,9 +4,8 @@ import (
 	"bytes"
 	"fmt"
 	"github.com/mmaaskant/gro-crop-scraper/attribute"
+	"github.com/mmaaskant/gro-crop-scraper/logger"
 	"golang.org/x/net/html"
-	"io"
-	"log"
 	"net/http"
 	"regexp"
 	"strings"
@@ -16,9 +15,10 @@ import (
 // HtmlCrawler crawls http(s) urls and returns their raw data,
 // as it uses http.Client.Do() nothing is rendered so data hidden in API calls will not be fetched.
 // HtmlCrawler is concurrency safe and keeps a registry of all found URLs.
+// Default headers, a rate limit and a Session can be set using the methods of its embedded fetcher.
 type HtmlCrawler struct {
 	*attribute.Tag
-	client      *http.Client
+	*fetcher
 	hrefRegex   *regexp.Regexp
 	urlRegex    map[*regexp.Regexp]string
 	urlRegistry map[string]string
@@ -29,7 +29,7 @@ func NewHtmlCrawler(c *http.Client) *HtmlCrawler {
 	r, _ := regexp.Compile(`(href="(/?)((\w*)/)*")`)
 	return &HtmlCrawler{
 		nil,
-		c,
+		newFetcher(c),
 		r,
 		make(map[*regexp.Regexp]string),
 		make(map[string]string),
@@ -54,38 +54,20 @@ func (hc *HtmlCrawler) AddExtractUrlRegex(expr string) {
 func (hc *HtmlCrawler) addRegex(expr string, requestType string) {
 	r, err := regexp.Compile(expr)
 	if err != nil {
-		log.Panicf("Failed to compile %s urlRegex %s, error: %s", requestType, expr, err)
+		logger.Panicf("Failed to compile %s urlRegex %s, error: %s", requestType, expr, err)
 	}
 	hc.urlRegex[r] = requestType
 }
 
 // Crawl crawls the given Call and returns the data and URLs it has found while doing so.
 func (hc *HtmlCrawler) Crawl(c *Call) *Data {
-	body, err := hc.do(c.Request)
+	body, resp, err := hc.do(hc.GetScraperId(), c)
 	if err != nil {
-		log.Printf("Failed to crawl url: %s, error: %s", c.URL.String(), err)
-		return NewData(hc.Tag, c, "", nil, err)
+		return NewData(hc.Tag, c, resp, "", nil, err)
 	}
 	cleanedBody, err := hc.clean(c.Request, body)
-	if err != nil {
-		log.Printf("Failed to clean HTML fetched from url %s, error: %s. Skipping ...", c.Request.URL.String(), err)
-	}
 	calls := hc.findCalls(cleanedBody)
-	return NewData(hc.Tag, c, body, calls, err)
-}
-
-// do calls the provided http.Request and cleans it by unescaping its body completing and any found partial urls.
-func (hc *HtmlCrawler) do(req *http.Request) (string, error) {
-	resp, err := hc.client.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
-	}
-	return string(body), err
+	return NewData(hc.Tag, c, resp, body, calls, err)
 }
 
 // findCalls uses the provided urlRegex to find urls and categorises them under either DiscoverRequestType or ExtractRequestType.
@@ -106,8 +88,7 @@ func (hc *HtmlCrawler) findCalls(body string) []*Call {
 func (hc *HtmlCrawler) clean(r *http.Request, body string) (string, error) {
 	node, err := html.Parse(strings.NewReader(body))
 	if err != nil {
-		log.Panicf("Failed to parse HTML %s, error: %s", body, err)
-		return "", err
+		return "", fmt.Errorf("failed to parse HTML, error: %w", err)
 	}
 	err = hc.formatUrls(r, node)
 	if err != nil {
@@ -167,6 +148,13 @@ func (hc *HtmlCrawler) formatHiddenUrls(req *http.Request, body string) (string,
 	return body, nil
 }
 
+// ResetUrlRegistry implements UrlRegistry.ResetUrlRegistry.
+func (hc *HtmlCrawler) ResetUrlRegistry() {
+	hc.mutex.Lock()
+	defer hc.mutex.Unlock()
+	hc.urlRegistry = make(map[string]string)
+}
+
 func (hc *HtmlCrawler) hasRegisteredUrl(url string) bool {
 	hc.mutex.RLock()
 	defer hc.mutex.RUnlock()
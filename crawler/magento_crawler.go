@@ -0,0 +1,111 @@
+package crawler
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/mmaaskant/gro-crop-scraper/logger"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultMagentoProductPathRegex matches the path of a Magento 2 product page, which is its URL key ending in ".html".
+	DefaultMagentoProductPathRegex = `([\w\-]*)\.html(\/)?`
+	// MagentoRestPageSize is the amount of products listed per page of the Magento REST API.
+	MagentoRestPageSize = 100
+)
+
+// NewMagentoHtmlCrawler returns a HtmlCrawler discovering the category pages of the Magento 2 shop at baseUrl,
+// e.g. "https://www.example.com", whose paths start with one of the given categories, including their "?p=" pages.
+// Product pages whose path matches productPathRegex are extracted, DefaultMagentoProductPathRegex is used if it is empty.
+func NewMagentoHtmlCrawler(c *http.Client, baseUrl string, categories []string, productPathRegex string) *HtmlCrawler {
+	host := regexp.QuoteMeta(magentoHost(baseUrl))
+	category := `[\w-]+`
+	if len(categories) > 0 {
+		category = strings.Join(categories, "|")
+	}
+	if productPathRegex == "" {
+		productPathRegex = DefaultMagentoProductPathRegex
+	}
+	hc := NewHtmlCrawler(c)
+	hc.AddDiscoveryUrlRegex(fmt.Sprintf(`(https?:\/\/)?%s\/?(%s)([\w\/-]*)(\?p=\d{1,3})?(&is_scroll=1)?`, host, category))
+	hc.AddExtractUrlRegex(fmt.Sprintf(`(https?:\/\/)?%s\/%s`, host, productPathRegex))
+	return hc
+}
+
+// NewMagentoHtmlCalls returns the Call kicking off the crawler returned by NewMagentoHtmlCrawler, which discovers
+// the home page of the shop at baseUrl.
+func NewMagentoHtmlCalls(baseUrl string) []*Call {
+	return []*Call{NewCall(NewRequest(http.MethodGet, baseUrl, nil), DiscoverRequestType)}
+}
+
+// NewMagentoRestCrawler returns a RestCrawler walking the pages of the product search of the Magento 2 REST API
+// at restUrl, e.g. "https://www.example.com/rest/V1", every product it lists is extracted from "/products/<sku>".
+// If token is set it is sent as a bearer token, as most shops do not allow anonymous access to their products.
+func NewMagentoRestCrawler(c *http.Client, restUrl string, token string) *RestCrawler {
+	rc := NewRestCrawler(c)
+	if token != "" {
+		rc.SetHeader("Authorization", "Bearer "+token)
+	}
+	rc.SetPaginator(&magentoPaginator{})
+	rc.AddExtractUrlField("items.*.sku", strings.TrimRight(restUrl, "/")+"/products/"+UrlFieldValue)
+	return rc
+}
+
+// NewMagentoRestCalls returns the Call kicking off the crawler returned by NewMagentoRestCrawler,
+// which only requests the SKU of every listed product.
+func NewMagentoRestCalls(restUrl string) []*Call {
+	query := url.Values{
+		"searchCriteria[pageSize]":    {strconv.Itoa(MagentoRestPageSize)},
+		"searchCriteria[currentPage]": {"1"},
+		"fields":                      {"items[sku],search_criteria,total_count"},
+	}
+	return []*Call{NewCall(
+		NewRequest(http.MethodGet, strings.TrimRight(restUrl, "/")+"/products?"+query.Encode(), nil),
+		DiscoverRequestType,
+	)}
+}
+
+// magentoPaginator paginates the search results of the Magento REST API until the total count has been listed,
+// as Magento keeps returning the last page for any page beyond it.
+type magentoPaginator struct{}
+
+// Next implements Paginator.Next.
+func (mp *magentoPaginator) Next(c *Call, resp *http.Response, body any) (*Call, error) {
+	page, pageSize, total := findJsonNumber(body, "search_criteria.current_page"),
+		findJsonNumber(body, "search_criteria.page_size"), findJsonNumber(body, "total_count")
+	if page <= 0 || pageSize <= 0 || page*pageSize >= total {
+		return nil, nil
+	}
+	query := c.Request.URL.Query()
+	query.Set("searchCriteria[currentPage]", strconv.Itoa(page+1))
+	u := *c.Request.URL
+	u.RawQuery = query.Encode()
+	return newPageCall(c, &u)
+}
+
+// findJsonNumber returns the number found at the given path within the decoded JSON value v, or 0 if there is none.
+func findJsonNumber(v any, path string) int {
+	values := findJsonValues(v, path)
+	if len(values) == 0 {
+		return 0
+	}
+	n, ok := values[0].(json.Number)
+	if !ok {
+		return 0
+	}
+	i, _ := strconv.Atoi(n.String())
+	return i
+}
+
+// magentoHost returns the host of the given base URL.
+func magentoHost(baseUrl string) string {
+	u, err := url.Parse(baseUrl)
+	if err != nil || u.Host == "" {
+		logger.With("url", baseUrl).Panicf("Invalid Magento base URL")
+	}
+	return u.Host
+}
@@ -1,26 +1,48 @@
 package crawler
 
 import (
+	"errors"
+	"fmt"
 	"github.com/mmaaskant/gophervisor/supervisor"
 	"github.com/mmaaskant/gro-crop-scraper/database"
 	"github.com/mmaaskant/gro-crop-scraper/helper"
-	"log"
+	"github.com/mmaaskant/gro-crop-scraper/logger"
+	"github.com/mmaaskant/gro-crop-scraper/metrics"
+	"go.mongodb.org/mongo-driver/mongo"
+	"net/http"
 	"reflect"
+	"runtime/debug"
+	"sync"
+	"time"
 )
 
+// StepId identifies the crawl step, e.g. within the counters of a run, logs and the "dead_letters" table.
+const StepId = "crawl"
+
 // Manager oversees all registered Crawler instances.
+// Every Crawler is crawled by its own workers, so a Crawler with many calls does not starve the others.
 type Manager struct {
-	db       *database.Db
-	crawlers map[Crawler][]*Call
+	db          *database.Db
+	crawlers    map[Crawler][]*Call
+	concurrency map[Crawler]*concurrency
+	limiters    map[Crawler]*hostLimiter
 }
 
 func NewManager(db *database.Db) *Manager {
 	return &Manager{
 		db,
 		make(map[Crawler][]*Call),
+		make(map[Crawler]*concurrency),
+		make(map[Crawler]*hostLimiter),
 	}
 }
 
+// concurrency limits the amount of calls of a single Crawler that are crawled at the same time, in total and per host.
+type concurrency struct {
+	workers     int
+	hostWorkers int
+}
+
 // crawlerJob holds a Crawler and a Call and is used to pass on units of work to Manager's workers.
 type crawlerJob struct {
 	crawler Crawler
@@ -44,43 +66,191 @@ func (m *Manager) RegisterCrawler(c Crawler, calls []*Call) {
 	m.crawlers[c] = calls
 }
 
-// Start begins crawling using the provided Crawler and Call instances,
-// a supervisor.Supervisor instance is used to crawl concurrently.
-func (m *Manager) Start(amountOfWorkers int) {
-	sv, p, _ := helper.StartSupervisor(amountOfWorkers, m.crawl)
+// SetConcurrency sets the amount of workers the given Crawler is crawled by, and the amount of its calls that are
+// crawled per host at the same time. An amount of workers of 0 uses the default amount of workers passed to Start,
+// and an amount of host workers of 0 does not limit calls per host.
+func (m *Manager) SetConcurrency(c Crawler, amountOfWorkers int, hostWorkers int) {
+	m.concurrency[c] = &concurrency{
+		amountOfWorkers,
+		hostWorkers,
+	}
+}
+
+// Start begins crawling using the provided Crawler and Call instances, every Crawler is crawled concurrently
+// by its own supervisor.Supervisor instance which starts the amount of workers set by SetConcurrency,
+// or defaultAmountOfWorkers if none was set. Start returns once all Crawler instances have finished.
+func (m *Manager) Start(defaultAmountOfWorkers int) {
+	for c := range m.crawlers {
+		if r, ok := c.(UrlRegistry); ok {
+			r.ResetUrlRegistry()
+		}
+		if cc := m.concurrency[c]; cc != nil {
+			m.limiters[c] = newHostLimiter(cc.hostWorkers)
+		}
+	}
+	wg := sync.WaitGroup{}
 	for c, calls := range m.crawlers {
-		for _, call := range calls {
-			p.Publish(newCrawlerJob(c, call))
+		amountOfWorkers := defaultAmountOfWorkers
+		if cc := m.concurrency[c]; cc != nil && cc.workers > 0 {
+			amountOfWorkers = cc.workers
 		}
+		sv, p, _ := helper.StartSupervisor(amountOfWorkers, m.crawl)
+		wg.Add(1)
+		go func(c Crawler, calls []*Call) {
+			defer wg.Done()
+			for _, call := range calls {
+				m.publish(p, newCrawlerJob(c, call))
+			}
+			sv.Shutdown()
+		}(c, calls)
 	}
-	sv.Shutdown()
+	wg.Wait()
+}
+
+// logger returns a logger.Logger holding the current run, the config and scraper ID of the crawlerJob's Crawler
+// and the URL of its Call.
+func (m *Manager) logger(cj *crawlerJob) *logger.Logger {
+	return logger.With(
+		"run_id", m.db.CurrentRun().Id(),
+		"config_id", cj.crawler.GetConfigId(),
+		"scraper_id", cj.crawler.GetScraperId(),
+		"step", StepId,
+		"url", cj.call.Request.URL.String(),
+	)
+}
+
+// publish queues the crawlerJob for Manager's workers and tracks the queue's depth.
+func (m *Manager) publish(p *supervisor.Publisher, cj *crawlerJob) {
+	metrics.QueueDepth.WithLabelValues(StepId).Inc()
+	p.Publish(cj)
 }
 
 // crawl receives crawlerJob instances and handles them,
 // this function is registered within supervisor.Supervisor as a worker.
+// Any error or panic that occurs while crawling is recorded in the "errors" table so the remaining jobs can continue.
 func (m *Manager) crawl(p *supervisor.Publisher, d any, rch chan any) {
 	var cj *crawlerJob
 	cj, ok := d.(*crawlerJob)
 	if !ok {
-		log.Panicf("Expected instance of %s, got %s", reflect.TypeOf(cj), reflect.TypeOf(d))
+		logger.Panicf("Expected instance of %s, got %s", reflect.TypeOf(cj), reflect.TypeOf(d))
 	}
-	cd := cj.crawler.Crawl(cj.call)
+	defer metrics.QueueDepth.WithLabelValues(StepId).Dec()
+	defer func() {
+		if r := recover(); r != nil {
+			m.recordError(cj, fmt.Errorf("crawler panicked: %v", r), debug.Stack())
+		}
+	}()
+	cd := m.crawlCall(cj)
 	if cd.Error != nil {
-		log.Printf("Crawler data contains error %s, skipping ...", cd.Error)
+		var be *BlockedError
+		if errors.As(cd.Error, &be) {
+			m.db.CurrentRun().Count(cj.crawler.GetScraperId(), StepId, "blocked")
+			metrics.BlockedResponses.WithLabelValues(cj.crawler.GetScraperId(), cj.call.Request.URL.Host).Inc()
+		}
+		m.recordError(cj, cd.Error, nil)
 		return
 	}
+	m.db.CurrentRun().Count(cj.crawler.GetScraperId(), StepId, "crawled")
 	for _, foundCall := range cd.FoundCalls {
-		p.Publish(newCrawlerJob(cj.crawler, foundCall))
+		m.publish(p, newCrawlerJob(cj.crawler, foundCall))
 	}
 	if cj.call.RequestType == ExtractRequestType {
-		err := m.db.InsertOne(database.NewEntity(database.ScrapedDataTableName, map[string]any{
-			"config_id":  cd.GetConfigId(),
-			"scraper_id": cd.GetScraperId(),
-			"url":        cd.Call.Request.URL.String(),
-			"data":       cd.Data,
-		}))
-		if err != nil {
-			log.Printf("Scraper failed to insert crawled HTML, error: %s", err)
+		if err := StoreData(m.db, cd, time.Now()); err != nil {
+			m.recordError(cj, fmt.Errorf("failed to store crawled data, error: %w", err), nil)
+			return
 		}
+		m.db.CurrentRun().Count(cj.crawler.GetScraperId(), StepId, "stored")
+		metrics.PagesExtracted.WithLabelValues(cj.crawler.GetScraperId()).Inc()
+	}
+}
+
+// crawlCall crawls the crawlerJob's Call once the Crawler's limit of calls per host allows it,
+// its request carries the logger of the crawlerJob so the Crawler logs along with the run, config and scraper.
+func (m *Manager) crawlCall(cj *crawlerJob) *Data {
+	defer m.limiters[cj.crawler].acquire(cj.call.Request.URL.Host)()
+	cj.call.Request = cj.call.Request.WithContext(logger.NewContext(cj.call.Request.Context(), m.logger(cj)))
+	return cj.crawler.Crawl(cj.call)
+}
+
+// StoreData saves the crawled Data in the "scraped_data" table along with its request and response metadata,
+// replacing the data of any earlier crawl of the same URL. Its filter version is cleared so the data is filtered again.
+func StoreData(db *database.Db, cd *Data, crawledAt time.Time) error {
+	url := cd.Call.Request.URL.String()
+	request := map[string]any{
+		"method": cd.Call.Request.Method,
+		"header": map[string][]string(cd.Call.Request.Header),
+	}
+	var response map[string]any
+	if cd.Response != nil {
+		response = map[string]any{
+			"status_code": cd.Response.StatusCode,
+			"proto":       cd.Response.Proto,
+			"header":      map[string][]string(cd.Response.Header),
+		}
+	}
+	e, err := db.GetOne(database.ScrapedDataTableName, map[string]any{"scraper_id": cd.GetScraperId(), "url": url})
+	if err != nil && err != mongo.ErrNoDocuments {
+		return err
+	}
+	if err == nil {
+		e.Data["data"] = cd.Data
+		e.Data["request"] = request
+		e.Data["response"] = response
+		e.Data["crawled_at"] = crawledAt
+		e.Data["filter_version"] = nil
+		db.StampRun(e)
+		return db.UpdateOne(e)
+	}
+	return db.InsertOne(database.NewEntity(database.ScrapedDataTableName, map[string]any{
+		"config_id":      cd.GetConfigId(),
+		"scraper_id":     cd.GetScraperId(),
+		"url":            url,
+		"data":           cd.Data,
+		"request":        request,
+		"response":       response,
+		"crawled_at":     crawledAt,
+		"filter_version": nil,
+	}))
+}
+
+// recordError logs the error and stores it in the "errors" table along with the call it occurred for,
+// the call is added to the "dead_letters" table so it can be requeued.
+func (m *Manager) recordError(cj *crawlerJob, err error, stack []byte) {
+	url := cj.call.Request.URL.String()
+	m.db.CurrentRun().Count(cj.crawler.GetScraperId(), StepId, "failed")
+	l := m.logger(cj)
+	l.With("error", err).Errorf("Failed to crawl url, skipping ...")
+	recordErr := m.db.RecordError(map[string]any{
+		"step":       StepId,
+		"config_id":  cj.crawler.GetConfigId(),
+		"scraper_id": cj.crawler.GetScraperId(),
+		"url":        url,
+	}, err, stack)
+	if recordErr != nil {
+		l.With("error", recordErr).Errorf("Failed to record crawler error")
+	}
+	recordErr = m.db.AddDeadLetter(StepId, cj.crawler.GetConfigId(), cj.crawler.GetScraperId(), url, map[string]any{
+		"method":       cj.call.Request.Method,
+		"url":          url,
+		"request_type": cj.call.RequestType,
+	}, err)
+	if recordErr != nil {
+		l.With("error", recordErr).Errorf("Failed to add crawler dead letter")
+	}
+}
+
+// NewCallFromDeadLetter recreates the Call held by a dead letter added by Manager.
+func NewCallFromDeadLetter(e *database.Entity) (*Call, error) {
+	item, ok := database.AsMap(e.Data["item"])
+	if !ok {
+		return nil, fmt.Errorf("dead letter %v does not hold a call", e.Id)
+	}
+	method, _ := item["method"].(string)
+	url, _ := item["url"].(string)
+	requestType, _ := item["request_type"].(string)
+	r, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return nil, err
 	}
+	return NewCall(r, requestType), nil
 }
@@ -14,25 +14,33 @@ var expected = map[string]*database.Entity{
 	"http://localhost:8080/extract-1.html/": database.NewEntity(
 		database.ScrapedDataTableName,
 		map[string]any{
-			"_id":        nil,
-			"config_id":  "test",
-			"scraper_id": "test_html",
-			"url":        "http://localhost:8080/extract-1.html/",
-			"data":       nil,
-			"created_at": nil,
-			"updated_at": nil,
+			"_id":            nil,
+			"config_id":      "test",
+			"scraper_id":     "test_html",
+			"url":            "http://localhost:8080/extract-1.html/",
+			"data":           nil,
+			"request":        nil,
+			"response":       nil,
+			"crawled_at":     nil,
+			"filter_version": nil,
+			"created_at":     nil,
+			"updated_at":     nil,
 		},
 	),
 	"http://localhost:8080/extract-2.html/": database.NewEntity(
 		database.ScrapedDataTableName,
 		map[string]any{
-			"_id":        nil,
-			"config_id":  "test",
-			"scraper_id": "test_html",
-			"url":        "http://localhost:8080/extract-2.html/",
-			"data":       nil,
-			"created_at": nil,
-			"updated_at": nil,
+			"_id":            nil,
+			"config_id":      "test",
+			"scraper_id":     "test_html",
+			"url":            "http://localhost:8080/extract-2.html/",
+			"data":           nil,
+			"request":        nil,
+			"response":       nil,
+			"crawled_at":     nil,
+			"filter_version": nil,
+			"created_at":     nil,
+			"updated_at":     nil,
 		},
 	),
 }
@@ -62,6 +70,9 @@ func TestCrawlerManager_Start(t *testing.T) {
 		ex.Id = e.Id
 		ex.Data["_id"] = e.Data["_id"]
 		ex.Data["data"] = e.Data["data"]
+		ex.Data["request"] = e.Data["request"]
+		ex.Data["response"] = e.Data["response"]
+		ex.Data["crawled_at"] = e.Data["crawled_at"]
 		ex.CreatedAt = e.CreatedAt
 		ex.Data["created_at"] = e.Data["created_at"]
 		if e.Id == nil {
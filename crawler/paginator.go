@@ -0,0 +1,215 @@
+package crawler
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Paginator finds the next page of a paginated REST API, e.g. a product listing, which is crawled as a new Call.
+type Paginator interface {
+	// Next returns the Call of the page following the page of the given Call, or nil if it was the last page.
+	// body holds the decoded JSON body of the response, or nil if the response is not JSON.
+	Next(c *Call, resp *http.Response, body any) (*Call, error)
+}
+
+// ParamPaginator paginates by incrementing a query param by step, which covers both page numbers, e.g. "page=2",
+// and offsets, e.g. "offset=50" where step is the amount of items per page. The param holds start if the Call
+// does not set it, and pagination stops once the items found at itemsPath within the response are empty.
+type ParamPaginator struct {
+	param     string
+	start     int
+	step      int
+	itemsPath string
+}
+
+// NewParamPaginator returns a ParamPaginator, itemsPath is a dot separated path as used by RestCrawler.AddExtractUrlField,
+// or an empty string if the response itself is the list of items.
+func NewParamPaginator(param string, start int, step int, itemsPath string) *ParamPaginator {
+	return &ParamPaginator{
+		param,
+		start,
+		step,
+		itemsPath,
+	}
+}
+
+// Next implements Paginator.Next.
+func (pp *ParamPaginator) Next(c *Call, resp *http.Response, body any) (*Call, error) {
+	if body == nil {
+		return nil, fmt.Errorf("paginating by %s requires a JSON response", pp.param)
+	}
+	if countItems(findJsonValues(body, pp.itemsPath)) == 0 {
+		return nil, nil
+	}
+	current := pp.start
+	query := c.Request.URL.Query()
+	if value := query.Get(pp.param); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("expected query param %s to be a number, got %q", pp.param, value)
+		}
+		current = n
+	}
+	query.Set(pp.param, strconv.Itoa(current+pp.step))
+	u := *c.Request.URL
+	u.RawQuery = query.Encode()
+	return newPageCall(c, &u)
+}
+
+// CursorPaginator paginates using a cursor found at cursorPath within the response, which is sent as the given
+// query param, e.g. "cursor=eyJpZCI6MTJ9". If param is empty, the cursor is expected to be the URL of the next page.
+// Pagination stops once the response no longer holds a cursor.
+type CursorPaginator struct {
+	cursorPath string
+	param      string
+}
+
+// NewCursorPaginator returns a CursorPaginator, cursorPath is a dot separated path as used by RestCrawler.AddExtractUrlField.
+func NewCursorPaginator(cursorPath string, param string) *CursorPaginator {
+	return &CursorPaginator{
+		cursorPath,
+		param,
+	}
+}
+
+// Next implements Paginator.Next.
+func (cp *CursorPaginator) Next(c *Call, resp *http.Response, body any) (*Call, error) {
+	if body == nil {
+		return nil, fmt.Errorf("paginating by cursor %s requires a JSON response", cp.cursorPath)
+	}
+	values := findJsonValues(body, cp.cursorPath)
+	if len(values) == 0 || values[0] == nil || fmt.Sprint(values[0]) == "" {
+		return nil, nil
+	}
+	cursor := fmt.Sprint(values[0])
+	if cp.param == "" {
+		u, err := c.Request.URL.Parse(cursor)
+		if err != nil {
+			return nil, fmt.Errorf("invalid next page url %q, error: %w", cursor, err)
+		}
+		return newPageCall(c, u)
+	}
+	query := c.Request.URL.Query()
+	query.Set(cp.param, cursor)
+	u := *c.Request.URL
+	u.RawQuery = query.Encode()
+	return newPageCall(c, &u)
+}
+
+// LinkHeaderPaginator paginates using the URL of the "next" relation within the Link header of the response,
+// e.g. `Link: <https://api.example.com/products?page_info=abc>; rel="next"`.
+type LinkHeaderPaginator struct{}
+
+func NewLinkHeaderPaginator() *LinkHeaderPaginator {
+	return &LinkHeaderPaginator{}
+}
+
+var linkRegex = regexp.MustCompile(`<([^>]*)>([^,<]*)`)
+var relRegex = regexp.MustCompile(`(?i)rel\s*=\s*"?([^";]*)"?`)
+
+// Next implements Paginator.Next.
+func (lp *LinkHeaderPaginator) Next(c *Call, resp *http.Response, body any) (*Call, error) {
+	if resp == nil {
+		return nil, nil
+	}
+	for _, header := range resp.Header.Values("Link") {
+		for _, link := range linkRegex.FindAllStringSubmatch(header, -1) {
+			rel := relRegex.FindStringSubmatch(link[2])
+			if rel == nil || !contains(strings.Fields(strings.ToLower(rel[1])), "next") {
+				continue
+			}
+			u, err := c.Request.URL.Parse(link[1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid next page url %q, error: %w", link[1], err)
+			}
+			return newPageCall(c, u)
+		}
+	}
+	return nil, nil
+}
+
+// newPageCall returns a Call requesting the given URL using the method, body and RequestType of the given Call.
+func newPageCall(c *Call, u *url.URL) (*Call, error) {
+	var body io.ReadCloser
+	if c.Request.GetBody != nil {
+		b, err := c.Request.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		body = b
+	}
+	req, err := http.NewRequest(c.Request.Method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	req.GetBody = c.Request.GetBody
+	req.ContentLength = c.Request.ContentLength
+	if contentType := c.Request.Header.Get("Content-Type"); contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	return NewCall(req, c.RequestType), nil
+}
+
+// findJsonValues returns all values found at the given dot separated path within the decoded JSON value v,
+// e.g. "data.products.*.url", where "*" matches every element of an array or every value of an object.
+// An empty path returns v itself.
+func findJsonValues(v any, path string) []any {
+	values := []any{v}
+	if path == "" {
+		return values
+	}
+	for _, key := range strings.Split(path, ".") {
+		found := make([]any, 0)
+		for _, value := range values {
+			switch t := value.(type) {
+			case map[string]any:
+				if key == "*" {
+					for _, child := range t {
+						found = append(found, child)
+					}
+				} else if child, ok := t[key]; ok {
+					found = append(found, child)
+				}
+			case []any:
+				if key == "*" {
+					found = append(found, t...)
+				} else if i, err := strconv.Atoi(key); err == nil && i >= 0 && i < len(t) {
+					found = append(found, t[i])
+				}
+			}
+		}
+		values = found
+	}
+	return values
+}
+
+// countItems returns the amount of items within the given values, arrays and objects count their elements.
+func countItems(values []any) int {
+	n := 0
+	for _, value := range values {
+		switch t := value.(type) {
+		case []any:
+			n += len(t)
+		case map[string]any:
+			n += len(t)
+		case nil:
+		default:
+			n++
+		}
+	}
+	return n
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package crawler
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+func TestRestCrawler_Paginate(t *testing.T) {
+	mux := http.NewServeMux()
+	// Lists two products per page over two pages, the third page is empty.
+	mux.HandleFunc("/products", func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page == 0 {
+			page = 1
+		}
+		if page > 2 {
+			_, _ = w.Write([]byte(`{"products": []}`))
+			return
+		}
+		_, _ = fmt.Fprintf(w, `{"products": [{"id": %d}, {"id": %d}]}`, page*2-1, page*2)
+	})
+	mux.HandleFunc("/cursor", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("cursor") == "abc" {
+			_, _ = w.Write([]byte(`{"items": [{"url": "/products/2"}], "next": null}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"items": [{"url": "/products/1"}], "next": "abc"}`))
+	})
+	mux.HandleFunc("/link", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page_info") == "" {
+			w.Header().Set("Link", `</link?page_info=xyz>; rel="next"`)
+		} else {
+			w.Header().Set("Link", `</link>; rel="previous"`)
+		}
+		_, _ = w.Write([]byte(`[{"url": "/products/3"}]`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := []struct {
+		name      string
+		path      string
+		paginator Paginator
+		field     string
+		template  string
+		expected  []string
+	}{
+		{
+			"page", "/products", NewParamPaginator("page", 1, 1, "products"), "products.*.id", "/products/{value}",
+			[]string{"/products/1", "/products/2", "/products/3", "/products/4"},
+		},
+		{
+			"cursor", "/cursor", NewCursorPaginator("next", "cursor"), "items.*.url", "",
+			[]string{"/products/1", "/products/2"},
+		},
+		{
+			"link header", "/link", NewLinkHeaderPaginator(), "*.url", "",
+			[]string{"/products/3"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc := NewRestCrawler(&http.Client{})
+			rc.SetPaginator(tt.paginator)
+			rc.AddExtractUrlField(tt.field, tt.template)
+			queue := []*Call{NewCall(NewRequest(http.MethodGet, server.URL+tt.path, nil), DiscoverRequestType)}
+			found := make([]string, 0)
+			for pages := 0; len(queue) > 0; pages++ {
+				if pages > 10 {
+					t.Fatalf("Expected pagination to stop")
+				}
+				cd := rc.Crawl(queue[0])
+				queue = queue[1:]
+				if cd.Error != nil {
+					t.Fatalf("Expected no error, got %s", cd.Error)
+				}
+				for _, c := range cd.FoundCalls {
+					if c.RequestType == ExtractRequestType {
+						found = append(found, c.Request.URL.Path)
+						continue
+					}
+					queue = append(queue, c)
+				}
+			}
+			sort.Strings(found)
+			if fmt.Sprint(found) != fmt.Sprint(tt.expected) {
+				t.Errorf("Expected %v to be found, got %v", tt.expected, found)
+			}
+		})
+	}
+}
+
+func TestRestCrawler_PaginateDiscoverOnly(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Link", `</products/1?page=2>; rel="next"`)
+		_, _ = w.Write([]byte(`{"id": 1}`))
+	}))
+	defer server.Close()
+	rc := NewRestCrawler(&http.Client{})
+	rc.SetPaginator(NewLinkHeaderPaginator())
+	cd := rc.Crawl(NewCall(NewRequest(http.MethodGet, server.URL+"/products/1", nil), ExtractRequestType))
+	if cd.Error != nil || len(cd.FoundCalls) != 0 {
+		t.Errorf("Expected an extracted response not to be paginated, got calls %v and error %v", cd.FoundCalls, cd.Error)
+	}
+}
@@ -1,23 +1,47 @@
 package crawler
 
 import (
+	"encoding/json"
+	"fmt"
 	"github.com/mmaaskant/gro-crop-scraper/attribute"
-	"io"
-	"log"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
 )
 
+// UrlFieldValue is replaced by the value of a URL field within its template, e.g. "https://api.example.com/products/{value}".
+const UrlFieldValue = "{value}"
+
 // RestCrawler crawls REST APIs using the provided Call instance.
+// Paginated responses are followed using its Paginator, and URLs found within JSON fields are returned as new calls.
+// RestCrawler is concurrency safe and keeps a registry of all found URLs.
+// Default headers, a rate limit and a Session can be set using the methods of its embedded fetcher.
 type RestCrawler struct {
 	*attribute.Tag
-	client *http.Client
+	*fetcher
+	paginator   Paginator
+	urlFields   []*urlField
+	urlRegistry map[string]string
+	mutex       sync.Mutex
+}
+
+// urlField holds a dot separated path to JSON fields holding URLs, or values which are inserted into the template.
+type urlField struct {
+	path        string
+	template    string
+	requestType string
 }
 
 // NewRestCrawler returns a new instance of RestCrawler.
 func NewRestCrawler(c *http.Client) *RestCrawler {
 	return &RestCrawler{
 		nil,
-		c,
+		newFetcher(c),
+		nil,
+		make([]*urlField, 0),
+		make(map[string]string),
+		sync.Mutex{},
 	}
 }
 
@@ -25,28 +49,112 @@ func (rc *RestCrawler) SetTag(t *attribute.Tag) {
 	rc.Tag = t
 }
 
+// SetPaginator sets the Paginator used to find the next page of every discovered response,
+// extracted responses are never paginated.
+func (rc *RestCrawler) SetPaginator(p Paginator) {
+	rc.paginator = p
+}
+
+// AddDiscoveryUrlField registers a dot separated path to JSON fields whose URLs should be collected for discovery,
+// e.g. "categories.*.url". If template is set, it is used to build the URL by replacing UrlFieldValue with the value.
+func (rc *RestCrawler) AddDiscoveryUrlField(path string, template string) {
+	rc.urlFields = append(rc.urlFields, &urlField{path, template, DiscoverRequestType})
+}
+
+// AddExtractUrlField registers a dot separated path to JSON fields whose URLs should be collected for extraction,
+// e.g. "products.*.id" along with the template "https://api.example.com/products/{value}".
+// If template is empty, the fields are expected to hold URLs, which may be relative to the URL of the response.
+func (rc *RestCrawler) AddExtractUrlField(path string, template string) {
+	rc.urlFields = append(rc.urlFields, &urlField{path, template, ExtractRequestType})
+}
+
 // Crawl starts crawling based on the given Call instance and returns a Data instance
 // containing the response as a string and any other relevant data found along the way.
 func (rc *RestCrawler) Crawl(c *Call) *Data {
-	b, err := rc.do(c.Request)
+	b, resp, err := rc.do(rc.GetScraperId(), c)
 	if err != nil {
-		log.Printf("Failed to crawl url: %s, error: %s", c.URL.String(), err)
-		return NewData(rc.Tag, c, "", nil, err)
+		return NewData(rc.Tag, c, resp, "", nil, err)
 	}
-	return NewData(rc.Tag, c, b, nil, err)
+	calls, err := rc.findCalls(c, resp, b)
+	return NewData(rc.Tag, c, resp, b, calls, err)
 }
 
-// do calls the provided http.Request, stringifies the result body and returns it.
-func (rc *RestCrawler) do(req *http.Request) (string, error) {
-	resp, err := rc.client.Do(req)
-	if err != nil {
-		log.Printf("Failed to call url: %s, error: %s", req.URL.String(), err)
-		return "", err
+// findCalls returns the Call of the next page along with calls for all URLs found within the registered URL fields.
+func (rc *RestCrawler) findCalls(c *Call, resp *http.Response, b string) ([]*Call, error) {
+	calls := make([]*Call, 0)
+	if rc.paginator == nil && len(rc.urlFields) == 0 {
+		return calls, nil
+	}
+	var body any
+	d := json.NewDecoder(strings.NewReader(b))
+	d.UseNumber()
+	if err := d.Decode(&body); err != nil {
+		if len(rc.urlFields) > 0 {
+			return calls, fmt.Errorf("failed to parse JSON, error: %w", err)
+		}
+		body = nil
+	}
+	if rc.paginator != nil && c.RequestType == DiscoverRequestType {
+		next, err := rc.paginator.Next(c, resp, body)
+		if err != nil {
+			return calls, fmt.Errorf("failed to find next page, error: %w", err)
+		}
+		if next != nil && rc.registerUrl(next.Request.URL.String(), next.RequestType) {
+			calls = append(calls, next)
+		}
 	}
-	defer resp.Body.Close()
-	b, err := io.ReadAll(resp.Body)
+	for _, f := range rc.urlFields {
+		for _, value := range findJsonValues(body, f.path) {
+			u, err := f.url(c.Request.URL, value)
+			if err != nil {
+				return calls, err
+			}
+			if u != "" && rc.registerUrl(u, f.requestType) {
+				calls = append(calls, NewCall(NewRequest(http.MethodGet, u, nil), f.requestType))
+			}
+		}
+	}
+	return calls, nil
+}
+
+// url returns the URL built from the given JSON value resolved against base, or an empty string if the value is empty.
+func (f *urlField) url(base *url.URL, value any) (string, error) {
+	if value == nil {
+		return "", nil
+	}
+	s := fmt.Sprint(value)
+	switch value.(type) {
+	case string, json.Number:
+	default:
+		return "", fmt.Errorf("expected URL field %s to hold a string or number, got %s", f.path, s)
+	}
+	if s == "" {
+		return "", nil
+	}
+	if f.template != "" {
+		s = strings.ReplaceAll(f.template, UrlFieldValue, url.PathEscape(s))
+	}
+	u, err := base.Parse(s)
 	if err != nil {
-		return "", err
+		return "", fmt.Errorf("invalid URL %q found within URL field %s, error: %w", s, f.path, err)
+	}
+	return u.String(), nil
+}
+
+// ResetUrlRegistry implements UrlRegistry.ResetUrlRegistry.
+func (rc *RestCrawler) ResetUrlRegistry() {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	rc.urlRegistry = make(map[string]string)
+}
+
+// registerUrl registers the given URL and returns false if it had already been registered.
+func (rc *RestCrawler) registerUrl(url string, requestType string) bool {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	if _, ok := rc.urlRegistry[url]; ok {
+		return false
 	}
-	return string(b), err
+	rc.urlRegistry[url] = requestType
+	return true
 }
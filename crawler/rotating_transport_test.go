@@ -0,0 +1,49 @@
+package crawler
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newStandInProxy returns a server acting as a proxy, which responds to every request itself with its name and the
+// User-Agent of the request, or with the given status code if it is not 200.
+func newStandInProxy(name string, status int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		_, _ = fmt.Fprintf(w, "%s %s", name, r.Header.Get("User-Agent"))
+	}))
+}
+
+func TestRotatingTransport_RoundTrip(t *testing.T) {
+	good, bad := newStandInProxy("good", http.StatusOK), newStandInProxy("bad", http.StatusBadGateway)
+	defer good.Close()
+	defer bad.Close()
+	rt, err := NewRotatingTransport([]string{good.URL, bad.URL}, []string{"agent-1", "agent-2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rt.SetBenchPolicy(2, 0.5, time.Minute)
+	rc := NewRestCrawler(&http.Client{Transport: rt})
+	responses := make(map[string]int)
+	for i := 0; i < 10; i++ {
+		d := rc.Crawl(NewCall(NewRequest(http.MethodGet, "http://supplier.test/product", nil), ExtractRequestType))
+		if d.Error != nil {
+			t.Fatal(d.Error)
+		}
+		responses[d.Data]++
+	}
+	// Requests alternate between both proxies and User-Agents until the bad proxy is benched after its second request,
+	// after which all requests are made through the good proxy using both User-Agents.
+	expected := map[string]int{"good agent-1": 5, "good agent-2": 3, "bad agent-2": 2}
+	for response, count := range expected {
+		if responses[response] != count {
+			t.Errorf("Expected %d responses %q, got %v", count, response, responses)
+		}
+	}
+	if _, err = NewRotatingTransport([]string{"not a proxy"}, nil); err == nil {
+		t.Errorf("Expected an invalid proxy url to fail")
+	}
+}
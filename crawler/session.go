@@ -0,0 +1,116 @@
+package crawler
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Session bootstraps the state a Crawler requires before it is able to crawl, e.g. logging in or selecting a region,
+// which is usually kept as cookies within the cookie jar of the Crawler's http.Client.
+type Session interface {
+	// Start starts a new session by sending its requests using the given function, which sends them like any other
+	// request of the Crawler. It is called before the first request is made and again whenever the session has expired.
+	Start(send func(req *http.Request) (*http.Response, error)) error
+	// Expired returns true if the given response, whose body has already been read, shows the session has expired.
+	Expired(resp *http.Response, body string) bool
+}
+
+// HttpSession is a Session which is started by making a sequence of requests, e.g. posting a login form
+// or getting a region selector, and which expires once a response has one of its expired status codes
+// or its body or URL matches its expired regex.
+type HttpSession struct {
+	steps              []*sessionStep
+	expiredStatusCodes []int
+	expiredRegex       *regexp.Regexp
+}
+
+func NewHttpSession() *HttpSession {
+	return &HttpSession{
+		make([]*sessionStep, 0),
+		make([]int, 0),
+		nil,
+	}
+}
+
+// sessionStep is a single request made to start a HttpSession, form values are sent url encoded.
+type sessionStep struct {
+	method string
+	url    string
+	form   url.Values
+}
+
+// AddRequest adds a request without a body, e.g. a GET request to a region selector.
+func (s *HttpSession) AddRequest(method string, url string) {
+	s.steps = append(s.steps, &sessionStep{method, url, nil})
+}
+
+// AddForm adds a POST request submitting the given form values, e.g. a login form.
+func (s *HttpSession) AddForm(url string, values url.Values) {
+	s.steps = append(s.steps, &sessionStep{http.MethodPost, url, values})
+}
+
+// SetExpiredStatusCodes sets the status codes of responses that show the session has expired, e.g. 401.
+func (s *HttpSession) SetExpiredStatusCodes(codes ...int) {
+	s.expiredStatusCodes = codes
+}
+
+// SetExpiredRegex sets a regex which shows the session has expired if it matches the body of a response or the URL
+// it was redirected to, e.g. the URL of a login page.
+func (s *HttpSession) SetExpiredRegex(expr string) error {
+	r, err := regexp.Compile(expr)
+	if err != nil {
+		return err
+	}
+	s.expiredRegex = r
+	return nil
+}
+
+// Start implements Session.Start and makes all requests in order, any response with an error status aborts the start.
+func (s *HttpSession) Start(send func(req *http.Request) (*http.Response, error)) error {
+	for _, step := range s.steps {
+		var body io.Reader
+		if step.form != nil {
+			body = strings.NewReader(step.form.Encode())
+		}
+		req, err := http.NewRequest(step.method, step.url, body)
+		if err != nil {
+			return fmt.Errorf("failed to create session request %s %s, error: %w", step.method, step.url, err)
+		}
+		if step.form != nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		resp, err := send(req)
+		if err != nil {
+			return fmt.Errorf("failed to start session using %s %s, error: %w", step.method, step.url, err)
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+		if resp.StatusCode >= http.StatusBadRequest {
+			return fmt.Errorf("failed to start session using %s %s, got status %s", step.method, step.url, resp.Status)
+		}
+	}
+	return nil
+}
+
+// Expired implements Session.Expired.
+func (s *HttpSession) Expired(resp *http.Response, body string) bool {
+	if resp == nil {
+		return false
+	}
+	for _, code := range s.expiredStatusCodes {
+		if resp.StatusCode == code {
+			return true
+		}
+	}
+	if s.expiredRegex == nil {
+		return false
+	}
+	if resp.Request != nil && s.expiredRegex.MatchString(resp.Request.URL.String()) {
+		return true
+	}
+	return s.expiredRegex.MatchString(body)
+}
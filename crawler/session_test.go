@@ -0,0 +1,57 @@
+package crawler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+)
+
+func TestHttpSession_Crawl(t *testing.T) {
+	var logins, requests int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("User-Agent") != DefaultUserAgent || r.Header.Get("Accept-Language") != "en-US" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if r.Method != http.MethodPost || r.FormValue("password") != "secret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		atomic.AddInt32(&logins, 1)
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "valid", Path: "/"})
+	})
+	mux.HandleFunc("/product", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("User-Agent") != DefaultUserAgent || r.Header.Get("Accept-Language") != "en-US" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		// The session expires after the first request.
+		if c, err := r.Cookie("session"); err != nil || c.Value != "valid" || atomic.AddInt32(&requests, 1) == 2 {
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "expired", Path: "/"})
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte("specs"))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	s := NewHttpSession()
+	s.AddForm(server.URL+"/login", url.Values{"password": {"secret"}})
+	s.SetExpiredStatusCodes(http.StatusUnauthorized)
+	rc := NewRestCrawler(&http.Client{})
+	rc.SetHeader("Accept-Language", "en-US")
+	rc.SetSession(s)
+	for i := 0; i < 2; i++ {
+		d := rc.Crawl(NewCall(NewRequest(http.MethodGet, server.URL+"/product", nil), ExtractRequestType))
+		if d.Error != nil || d.Data != "specs" {
+			t.Fatalf("Expected request %d to return specs, got %q and error %v", i, d.Data, d.Error)
+		}
+	}
+	if logins != 2 {
+		t.Errorf("Expected the session to be started again once it expired, got %d logins", logins)
+	}
+}
@@ -0,0 +1,36 @@
+package crawler
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// ShopifyPageSize is the largest amount of products a Shopify shop lists per page of "/products.json".
+	ShopifyPageSize = 250
+	// ShopifyRateLimit spaces out requests to a Shopify shop, as its storefront rate limits clients quickly.
+	ShopifyRateLimit = 500 * time.Millisecond
+)
+
+// NewShopifyCrawler returns a RestCrawler walking the pages of a Shopify shop's "/products.json" listing,
+// every product it lists is extracted from "/products/<handle>.js". Responses with status 429 are blocked,
+// pausing requests to the shop for a while.
+func NewShopifyCrawler(c *http.Client) *RestCrawler {
+	rc := NewRestCrawler(c)
+	rc.SetRateLimit(ShopifyRateLimit)
+	rc.SetPaginator(NewParamPaginator("page", 1, 1, "products"))
+	rc.AddExtractUrlField("products.*.handle", "/products/"+UrlFieldValue+".js")
+	rc.AddBlockDetector(NewStatusBlockDetector(http.StatusTooManyRequests))
+	return rc
+}
+
+// NewShopifyCalls returns the Call kicking off the crawler returned by NewShopifyCrawler for the shop at shopUrl,
+// e.g. "https://shop.example.com", or one of its collections, e.g. "https://shop.example.com/collections/seeds".
+func NewShopifyCalls(shopUrl string) []*Call {
+	return []*Call{NewCall(
+		NewRequest(http.MethodGet, fmt.Sprintf("%s/products.json?limit=%d", strings.TrimRight(shopUrl, "/"), ShopifyPageSize), nil),
+		DiscoverRequestType,
+	)}
+}
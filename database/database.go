@@ -1,13 +1,24 @@
 package database
 
+import (
+	"github.com/mmaaskant/gro-crop-scraper/metrics"
+	"time"
+)
+
 const ScrapedDataTableName = "scraped_data"
 const FilteredDataTableName = "filtered_data"
 
+// ErrorTableName holds failures that occurred while processing a single document or call, so a run can continue.
+const ErrorTableName = "errors"
+
 // Db is a facade that holds an instance of Driver and forwards its functions,
 // Driver is interchangeable and allows the changing of database types.
+// Raw bodies, like those held by the "scraped_data" table, are transparently compressed and stored once
+// within the "raw_bodies" table, and the ID of the current run is stamped on every document written.
 // Db currently does not support context.Context.
 type Db struct {
 	Driver
+	run *Run
 }
 
 // NewDb creates a new Db instance and attempts to connect the Driver to its database and
@@ -19,5 +30,98 @@ func NewDb(d Driver) (*Db, error) {
 	}
 	return &Db{
 		d,
+		nil,
 	}, err
 }
+
+// GetOne forwards Driver.GetOne and hydrates the Entity's raw body.
+func (db *Db) GetOne(table string, params map[string]any) (*Entity, error) {
+	e, err := db.Driver.GetOne(table, params)
+	if err != nil {
+		return nil, err
+	}
+	if err = db.hydrate(e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// GetMany forwards Driver.GetMany and hydrates the raw body of every Entity returned by the ResultIterator.
+func (db *Db) GetMany(table string, params map[string]any) (ResultIterator, error) {
+	iterator, err := db.Driver.GetMany(table, params)
+	if err != nil {
+		return nil, err
+	}
+	return &rawBodyResultIterator{db, iterator}, nil
+}
+
+// InsertOne stamps the current run, stores the Entity's raw body and forwards Driver.InsertOne.
+func (db *Db) InsertOne(e *Entity) error {
+	db.StampRun(e)
+	stored, err := db.dehydrate(e)
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	err = db.Driver.InsertOne(stored)
+	metrics.ObserveDbWrite("insert_one", e.Table, start, err)
+	if err != nil {
+		return err
+	}
+	db.sync(e, stored)
+	return nil
+}
+
+// InsertMany stamps the current run, stores the raw bodies of all Entity instances and forwards Driver.InsertMany.
+func (db *Db) InsertMany(entities []*Entity) error {
+	stored := make([]*Entity, len(entities))
+	for i, e := range entities {
+		db.StampRun(e)
+		s, err := db.dehydrate(e)
+		if err != nil {
+			return err
+		}
+		stored[i] = s
+	}
+	start := time.Now()
+	err := db.Driver.InsertMany(stored)
+	if len(entities) > 0 {
+		metrics.ObserveDbWrite("insert_many", entities[0].Table, start, err)
+	}
+	if err != nil {
+		return err
+	}
+	for i, e := range entities {
+		db.sync(e, stored[i])
+	}
+	return nil
+}
+
+// UpdateOne stores the Entity's raw body and forwards Driver.UpdateOne, the run the Entity was written by is kept.
+func (db *Db) UpdateOne(e *Entity) error {
+	stored, err := db.dehydrate(e)
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	err = db.Driver.UpdateOne(stored)
+	metrics.ObserveDbWrite("update_one", e.Table, start, err)
+	if err != nil {
+		return err
+	}
+	db.sync(e, stored)
+	return nil
+}
+
+// RecordError stores the given error and stack in the ErrorTableName table,
+// details describe what failed to be processed, e.g. the document ID and scraper ID.
+func (db *Db) RecordError(details map[string]any, err error, stack []byte) error {
+	data := map[string]any{
+		"error": err.Error(),
+		"stack": string(stack),
+	}
+	for k, v := range details {
+		data[k] = v
+	}
+	return db.InsertOne(NewEntity(ErrorTableName, data))
+}
@@ -0,0 +1,40 @@
+package database
+
+import (
+	"time"
+)
+
+// DeadLetterTableName holds items that a step failed to process, so they can be inspected and requeued later on.
+const DeadLetterTableName = "dead_letters"
+
+const (
+	// DeadLetterFailedStatus marks a dead letter whose item failed to be processed.
+	DeadLetterFailedStatus = "failed"
+	// DeadLetterRequeuedStatus marks a dead letter whose item has been requeued and has not failed again since.
+	DeadLetterRequeuedStatus = "requeued"
+	// DeadLetterMissingStatus marks a dead letter whose item could not be requeued as it no longer exists,
+	// e.g. a scraped document that has been purged.
+	DeadLetterMissingStatus = "missing"
+)
+
+// AddDeadLetter stores an item that a step failed to process in the DeadLetterTableName table,
+// the item holds everything the step requires to process it again, e.g. the call or the ID of the scraped document.
+// Dead letters are identified by their step, scraper ID and key (usually a URL), if the item has failed before
+// its attempt count is increased and its error is replaced.
+func (db *Db) AddDeadLetter(step string, configId string, scraperId string, key string, item map[string]any, err error) error {
+	set := map[string]any{
+		"config_id": configId,
+		"item":      item,
+		"error":     err.Error(),
+		"status":    DeadLetterFailedStatus,
+		"failed_at": time.Now(),
+	}
+	if db.run != nil {
+		set["run_id"] = db.run.Id()
+	}
+	return db.Driver.UpsertOne(
+		DeadLetterTableName,
+		map[string]any{"step": step, "scraper_id": scraperId, "key": key},
+		map[string]any{"$set": set, "$inc": map[string]any{"attempts": int32(1)}},
+	)
+}
@@ -0,0 +1,26 @@
+package database
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDb_AddDeadLetter(t *testing.T) {
+	db := newDb(t)
+	item := map[string]any{"url": "https://example.com"}
+	for _, err := range []error{errors.New("first failure"), errors.New("second failure")} {
+		if err := db.AddDeadLetter("crawl", "test", "test_html", "https://example.com", item, err); err != nil {
+			t.Errorf("Failed to add dead letter, error: %s", err)
+		}
+	}
+	e, err := db.GetOne(DeadLetterTableName, map[string]any{"scraper_id": "test_html", "key": "https://example.com"})
+	if err != nil {
+		t.Fatalf("Failed to get dead letter from DB, error: %s", err)
+	}
+	if e.Data["attempts"] != int32(2) || e.Data["error"] != "second failure" || e.Data["status"] != DeadLetterFailedStatus {
+		t.Errorf("Got dead letter %v, expected 2 attempts with the last error and status %s", e.Data, DeadLetterFailedStatus)
+	}
+	if err = db.DeleteMany(DeadLetterTableName, map[string]any{"config_id": "test"}); err != nil {
+		t.Errorf("Failed to delete dead letters in DB, error: %s", err)
+	}
+}
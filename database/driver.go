@@ -1,6 +1,7 @@
 package database
 
 import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"time"
 )
 
@@ -15,8 +16,15 @@ type Driver interface {
 	InsertMany(entities []*Entity) error
 	UpdateOne(e *Entity) error
 	UpdateMany(table string, filter map[string]any, update map[string]any) error
+	// UpdateFirst updates the first row matching the filter and returns false if no row matched.
+	UpdateFirst(table string, filter map[string]any, update map[string]any) (bool, error)
+	// UpsertOne updates the row matching the filter using the given update operators, e.g. "$set" and "$inc",
+	// or inserts it if no row matches.
+	UpsertOne(table string, filter map[string]any, update map[string]any) error
 	DeleteOne(e *Entity) error
 	DeleteMany(table string, filter map[string]any) error
+	// ParseId converts the string representation of an ID to the type the database uses.
+	ParseId(id string) (any, error)
 }
 
 type ResultIterator interface {
@@ -53,6 +61,19 @@ func (e *Entity) GetString(key string) *string {
 	return nil
 }
 
+// AsMap converts a nested document to a map, documents fetched by Driver may hold nested documents
+// of a named map type like primitive.M which do not match map[string]any directly.
+func AsMap(v any) (map[string]any, bool) {
+	switch m := v.(type) {
+	case map[string]any:
+		return m, true
+	case primitive.M:
+		return m, true
+	default:
+		return nil, false
+	}
+}
+
 func NewEntity(table string, data map[string]any) *Entity {
 	return &Entity{
 		Id:        nil,
@@ -0,0 +1,47 @@
+package database
+
+import (
+	"go.mongodb.org/mongo-driver/mongo"
+	"time"
+)
+
+// LockTableName holds locks that prevent processes from running the same work at the same time,
+// every lock is a document whose ID is the name of the lock.
+const LockTableName = "locks"
+
+// AcquireLock attempts to acquire the lock with the given name for the given owner and returns false if it is held
+// by another owner. A lock expires after the given ttl unless it is refreshed, so a lock held by a process that was
+// killed does not block others indefinitely.
+func (db *Db) AcquireLock(name string, owner string, ttl time.Duration) (bool, error) {
+	err := db.Driver.DeleteMany(LockTableName, map[string]any{
+		"_id":        name,
+		"expires_at": map[string]any{"$lt": time.Now()},
+	})
+	if err != nil {
+		return false, err
+	}
+	err = db.Driver.InsertOne(NewEntity(LockTableName, map[string]any{
+		"_id":         name,
+		"owner":       owner,
+		"acquired_at": time.Now(),
+		"expires_at":  time.Now().Add(ttl),
+	}))
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// RefreshLock extends the lock with the given name held by the given owner by the given ttl, and returns true if the
+// lock was lost because it expired and was acquired by another owner or was released in the meantime.
+func (db *Db) RefreshLock(name string, owner string, ttl time.Duration) (bool, error) {
+	matched, err := db.Driver.UpdateFirst(LockTableName, map[string]any{"_id": name, "owner": owner}, map[string]any{
+		"expires_at": time.Now().Add(ttl),
+	})
+	return err == nil && !matched, err
+}
+
+// ReleaseLock releases the lock with the given name if it is held by the given owner.
+func (db *Db) ReleaseLock(name string, owner string) error {
+	return db.Driver.DeleteMany(LockTableName, map[string]any{"_id": name, "owner": owner})
+}
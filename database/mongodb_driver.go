@@ -2,11 +2,11 @@ package database
 
 import (
 	"context"
+	"github.com/mmaaskant/gro-crop-scraper/logger"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
-	"log"
 	"os"
 	"reflect"
 	"time"
@@ -43,7 +43,7 @@ func (mdri *MongoDbResultIterator) Next() (*Entity, error) {
 		return nil, nil
 	}
 	if err := bson.Unmarshal(mdri.cursor.Current, &data); err != nil {
-		log.Panicf("Failed to unmarshal cursor next result, error: %s", err)
+		logger.With("table", mdri.table).Panicf("Failed to unmarshal cursor next result, error: %s", err)
 	}
 	return mdri.driver.hydrateEntity(mdri.table, data), nil
 }
@@ -88,7 +88,7 @@ func (mdd *MongoDbDriver) hydrateEntity(table string, m bson.M) *Entity {
 func (mdd *MongoDbDriver) bsonMarshal(d any) []byte {
 	r, err := bson.Marshal(d)
 	if err != nil {
-		log.Panicf("BSON Marshal err: %s, Value: %v", err, d)
+		logger.Panicf("BSON Marshal err: %s, Value: %v", err, d)
 	}
 	return r
 }
@@ -101,7 +101,7 @@ func (mdd *MongoDbDriver) convertToTime(v any) *time.Time {
 	var pdt primitive.DateTime
 	pdt, ok := v.(primitive.DateTime)
 	if !ok {
-		log.Panicf(
+		logger.Panicf(
 			"Expected instance of %s while converting to %s, got: %s",
 			reflect.TypeOf(pdt),
 			reflect.TypeOf((*time.Time)(nil)),
@@ -173,6 +173,37 @@ func (mdd *MongoDbDriver) UpdateMany(table string, filter map[string]any, update
 	return err
 }
 
+// UpdateFirst updates the first row within MongoDB matching the provided filter and returns false if none matched.
+func (mdd *MongoDbDriver) UpdateFirst(table string, filter map[string]any, update map[string]any) (bool, error) {
+	update["updated_at"] = primitive.NewDateTimeFromTime(time.Now())
+	update = map[string]any{"$set": update}
+	r, err := mdd.db.Collection(table).UpdateOne(context.TODO(), mdd.bsonMarshal(filter), mdd.bsonMarshal(update))
+	if err != nil {
+		return false, err
+	}
+	return r.MatchedCount > 0, nil
+}
+
+// UpsertOne updates a single row within MongoDB based on the provided filter and update operators, or inserts it
+// if none matches. The update operators are applied atomically, so concurrent upserts do not lose updates.
+func (mdd *MongoDbDriver) UpsertOne(table string, filter map[string]any, update map[string]any) error {
+	now := primitive.NewDateTimeFromTime(time.Now())
+	set, ok := update["$set"].(map[string]any)
+	if !ok {
+		set = make(map[string]any)
+		update["$set"] = set
+	}
+	set["updated_at"] = now
+	update["$setOnInsert"] = map[string]any{"created_at": now}
+	_, err := mdd.db.Collection(table).UpdateOne(
+		context.TODO(),
+		mdd.bsonMarshal(filter),
+		mdd.bsonMarshal(update),
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
 func (mdd *MongoDbDriver) setUpdatedAt(e *Entity) {
 	t := time.Now()
 	e.UpdatedAt = &t
@@ -180,7 +211,7 @@ func (mdd *MongoDbDriver) setUpdatedAt(e *Entity) {
 }
 
 func (mdd *MongoDbDriver) DeleteOne(e *Entity) error {
-	_, err := mdd.db.Collection(e.Table).DeleteOne(context.TODO(), bson.D{{"_id", e.Id}})
+	_, err := mdd.db.Collection(e.Table).DeleteOne(context.TODO(), bson.D{{Key: "_id", Value: e.Id}})
 	return err
 }
 
@@ -189,3 +220,8 @@ func (mdd *MongoDbDriver) DeleteMany(table string, filter map[string]any) error
 	_, err := mdd.db.Collection(table).DeleteMany(context.TODO(), mdd.bsonMarshal(filter))
 	return err
 }
+
+// ParseId converts a hexadecimal string to a primitive.ObjectID.
+func (mdd *MongoDbDriver) ParseId(id string) (any, error) {
+	return primitive.ObjectIDFromHex(id)
+}
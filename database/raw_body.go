@@ -0,0 +1,274 @@
+package database
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/klauspost/compress/zstd"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"io"
+	"os"
+	"time"
+)
+
+// RawBodyTableName holds compressed raw bodies addressed by the hash of their content, so identical bodies are stored once.
+const RawBodyTableName = "raw_bodies"
+
+const (
+	ZstdEncoding = "zstd"
+	GzipEncoding = "gzip"
+)
+
+// rawBodyFields maps tables to the field that holds their raw body, these are stored within the RawBodyTableName table
+// and replaced by a reference to it named "body_hash".
+var rawBodyFields = map[string]string{
+	ScrapedDataTableName: "data",
+}
+
+// RawBodyPurgeGracePeriod is how long a raw body is kept after it was last stored, even if no document references it,
+// so a body is not purged between being stored and the document referencing it being written.
+const RawBodyPurgeGracePeriod = time.Hour
+
+var zstdEncoder, _ = zstd.NewWriter(nil)
+var zstdDecoder, _ = zstd.NewReader(nil)
+
+// compress compresses the given body using the given encoding.
+func compress(body []byte, encoding string) ([]byte, error) {
+	switch encoding {
+	case ZstdEncoding:
+		return zstdEncoder.EncodeAll(body, nil), nil
+	case GzipEncoding:
+		buf := new(bytes.Buffer)
+		w := gzip.NewWriter(buf)
+		if _, err := w.Write(body); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	default:
+		return nil, fmt.Errorf("unknown raw body encoding %s", encoding)
+	}
+}
+
+// decompress decompresses the given body using the given encoding.
+func decompress(body []byte, encoding string) ([]byte, error) {
+	switch encoding {
+	case ZstdEncoding:
+		return zstdDecoder.DecodeAll(body, nil)
+	case GzipEncoding:
+		r, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return io.ReadAll(r)
+	default:
+		return nil, fmt.Errorf("unknown raw body encoding %s", encoding)
+	}
+}
+
+// rawBodyEncoding returns the encoding set by the RAW_BODY_COMPRESSION env variable, zstd is used if it is empty.
+func rawBodyEncoding() string {
+	if encoding := os.Getenv("RAW_BODY_COMPRESSION"); encoding != "" {
+		return encoding
+	}
+	return ZstdEncoding
+}
+
+// storeRawBody stores the given body within the RawBodyTableName table and returns the hash it is addressed by,
+// if it has been stored before only its "updated_at" field is refreshed so PurgeRawBodies keeps it.
+func (db *Db) storeRawBody(body string) (string, error) {
+	sum := sha256.Sum256([]byte(body))
+	hash := hex.EncodeToString(sum[:])
+	_, err := db.Driver.GetOne(RawBodyTableName, map[string]any{"_id": hash})
+	if err == nil {
+		return hash, db.Driver.UpdateMany(RawBodyTableName, map[string]any{"_id": hash}, map[string]any{})
+	}
+	if err != mongo.ErrNoDocuments {
+		return "", err
+	}
+	encoding := rawBodyEncoding()
+	compressed, err := compress([]byte(body), encoding)
+	if err != nil {
+		return "", err
+	}
+	err = db.Driver.InsertOne(NewEntity(RawBodyTableName, map[string]any{
+		"_id":      hash,
+		"encoding": encoding,
+		"size":     len(body),
+		"body":     compressed,
+	}))
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		return "", err
+	}
+	return hash, nil
+}
+
+// loadRawBody fetches and decompresses the body addressed by the given hash.
+func (db *Db) loadRawBody(hash string) (string, error) {
+	e, err := db.Driver.GetOne(RawBodyTableName, map[string]any{"_id": hash})
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch raw body %s, error: %w", hash, err)
+	}
+	var compressed []byte
+	switch b := e.Data["body"].(type) {
+	case []byte:
+		compressed = b
+	case primitive.Binary:
+		compressed = b.Data
+	default:
+		return "", fmt.Errorf("raw body %s holds unexpected type %T", hash, e.Data["body"])
+	}
+	body, err := decompress(compressed, fmt.Sprint(e.Data["encoding"]))
+	if err != nil {
+		return "", fmt.Errorf("failed to decompress raw body %s, error: %w", hash, err)
+	}
+	return string(body), nil
+}
+
+// dehydrate returns a copy of the given Entity in which its raw body has been replaced by a reference to the
+// RawBodyTableName table, or the Entity itself if its table does not hold raw bodies.
+func (db *Db) dehydrate(e *Entity) (*Entity, error) {
+	field, ok := rawBodyFields[e.Table]
+	if !ok {
+		return e, nil
+	}
+	body, ok := e.Data[field].(string)
+	if !ok {
+		return e, nil
+	}
+	hash, err := db.storeRawBody(body)
+	if err != nil {
+		return nil, err
+	}
+	stored := *e
+	stored.Data = make(map[string]any, len(e.Data))
+	for k, v := range e.Data {
+		stored.Data[k] = v
+	}
+	delete(stored.Data, field)
+	stored.Data["body_hash"] = hash
+	return &stored, nil
+}
+
+// hydrate replaces the reference to the RawBodyTableName table held by the given Entity with its raw body.
+func (db *Db) hydrate(e *Entity) error {
+	field, ok := rawBodyFields[e.Table]
+	if !ok {
+		return nil
+	}
+	hash, ok := e.Data["body_hash"].(string)
+	if !ok {
+		return nil
+	}
+	body, err := db.loadRawBody(hash)
+	if err != nil {
+		return err
+	}
+	e.Data[field] = body
+	delete(e.Data, "body_hash")
+	return nil
+}
+
+// sync copies the fields set by Driver while storing a dehydrated Entity back to the original Entity.
+func (db *Db) sync(e *Entity, stored *Entity) {
+	if stored == e {
+		return
+	}
+	e.Id = stored.Id
+	e.CreatedAt = stored.CreatedAt
+	e.UpdatedAt = stored.UpdatedAt
+	for _, k := range []string{"_id", "created_at", "updated_at"} {
+		if v, ok := stored.Data[k]; ok {
+			e.Data[k] = v
+		}
+	}
+}
+
+// rawBodyResultIterator implements ResultIterator and hydrates the Entity instances returned by its ResultIterator.
+type rawBodyResultIterator struct {
+	db       *Db
+	iterator ResultIterator
+}
+
+func (rbri *rawBodyResultIterator) Next() (*Entity, error) {
+	e, err := rbri.iterator.Next()
+	if e == nil || err != nil {
+		return e, err
+	}
+	if err = rbri.db.hydrate(e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// MigrateRawBodies moves the raw bodies of all documents that still hold them directly to the RawBodyTableName table,
+// and returns the amount of documents that have been migrated.
+func (db *Db) MigrateRawBodies() (int, error) {
+	migrated := 0
+	for table, field := range rawBodyFields {
+		iterator, err := db.Driver.GetMany(table, map[string]any{"body_hash": nil})
+		if err != nil {
+			return migrated, err
+		}
+		for e, _ := iterator.Next(); e != nil; e, _ = iterator.Next() {
+			if _, ok := e.Data[field].(string); !ok {
+				continue
+			}
+			if err = db.UpdateOne(e); err != nil {
+				return migrated, fmt.Errorf("failed to migrate raw body of %v, error: %w", e.Id, err)
+			}
+			migrated++
+		}
+	}
+	return migrated, nil
+}
+
+// PurgeRawBodies removes all raw bodies that are no longer referenced by any document,
+// bodies stored within the RawBodyPurgeGracePeriod are kept as the documents referencing them may not be written yet.
+func (db *Db) PurgeRawBodies() error {
+	cutoff := time.Now().Add(-RawBodyPurgeGracePeriod)
+	iterator, err := db.Driver.GetMany(RawBodyTableName, map[string]any{
+		"created_at": map[string]any{"$lt": cutoff},
+		"$or": []any{
+			map[string]any{"updated_at": nil},
+			map[string]any{"updated_at": map[string]any{"$lt": cutoff}},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	for e, _ := iterator.Next(); e != nil; e, _ = iterator.Next() {
+		referenced, err := db.isRawBodyReferenced(e.Id)
+		if err != nil {
+			return err
+		}
+		if referenced {
+			continue
+		}
+		if err = db.Driver.DeleteOne(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isRawBodyReferenced returns whether any document still references the raw body with the given hash,
+// the lookup relies on the body_hash index of every table holding raw bodies.
+func (db *Db) isRawBodyReferenced(hash any) (bool, error) {
+	for table := range rawBodyFields {
+		_, err := db.Driver.GetOne(table, map[string]any{"body_hash": hash})
+		if err == nil {
+			return true, nil
+		}
+		if err != mongo.ErrNoDocuments {
+			return false, err
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,26 @@
+package database
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompress(t *testing.T) {
+	body := strings.Repeat("<div class=\"product\">Tomato, Sungold Hybrid</div>", 100)
+	for _, encoding := range []string{ZstdEncoding, GzipEncoding} {
+		compressed, err := compress([]byte(body), encoding)
+		if err != nil {
+			t.Fatalf("Failed to compress using %s, error: %s", encoding, err)
+		}
+		if len(compressed) >= len(body) {
+			t.Errorf("Expected %s to compress %d bytes, got: %d bytes", encoding, len(body), len(compressed))
+		}
+		decompressed, err := decompress(compressed, encoding)
+		if err != nil {
+			t.Fatalf("Failed to decompress using %s, error: %s", encoding, err)
+		}
+		if string(decompressed) != body {
+			t.Errorf("Expected %s to decompress to the original body", encoding)
+		}
+	}
+}
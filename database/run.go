@@ -0,0 +1,142 @@
+package database
+
+import (
+	"sync"
+	"time"
+)
+
+// RunTableName holds a record of every run, its flags, configs, start and end, and counters per scraper and step.
+const RunTableName = "runs"
+
+const (
+	// RunRunningStatus marks a run that has started and has not finished yet, or was killed before it could finish.
+	RunRunningStatus = "running"
+	// RunFinishedStatus marks a run that has finished.
+	RunFinishedStatus = "finished"
+	// RunFailedStatus marks a run that was aborted by an error.
+	RunFailedStatus = "failed"
+)
+
+// RunFlushInterval determines how often the counters of a run in progress are stored by Db.FlushRun,
+// so the progress of a run can be followed and a run that was killed still holds the counters of its last flush.
+const RunFlushInterval = 30 * time.Second
+
+// Run tracks a single invocation of the scraper, its counters are safe for concurrent use by a step's workers.
+// All methods of Run can be called on nil, in which case nothing is tracked.
+type Run struct {
+	entity   *Entity
+	mu       sync.Mutex
+	counters map[string]map[string]map[string]int
+}
+
+// Id returns the ID of the run, which is stamped on every document written while it is running.
+func (r *Run) Id() any {
+	if r == nil {
+		return nil
+	}
+	return r.entity.Id
+}
+
+// Count increases the given counter of the given scraper and step by one, e.g. the amount of pages crawled.
+func (r *Run) Count(scraperId string, step string, counter string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.counters[scraperId] == nil {
+		r.counters[scraperId] = make(map[string]map[string]int)
+	}
+	if r.counters[scraperId][step] == nil {
+		r.counters[scraperId][step] = make(map[string]int)
+	}
+	r.counters[scraperId][step][counter]++
+}
+
+// Counters returns a copy of the counters tracked so far, mapped by scraper ID, step and counter.
+func (r *Run) Counters() map[string]any {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	scrapers := make(map[string]any, len(r.counters))
+	for scraperId, steps := range r.counters {
+		stepCounters := make(map[string]any, len(steps))
+		for step, counters := range steps {
+			c := make(map[string]any, len(counters))
+			for k, v := range counters {
+				c[k] = v
+			}
+			stepCounters[step] = c
+		}
+		scrapers[scraperId] = stepCounters
+	}
+	return scrapers
+}
+
+// StartRun stores a new run in the RunTableName table and stamps its ID on every document written
+// through Db until FinishRun is called.
+func (db *Db) StartRun(flags []string, configIds []string) (*Run, error) {
+	e := NewEntity(RunTableName, map[string]any{
+		"flags":      flags,
+		"configs":    configIds,
+		"status":     RunRunningStatus,
+		"started_at": time.Now(),
+		"ended_at":   nil,
+		"counters":   map[string]any{},
+		"error":      nil,
+	})
+	if err := db.Driver.InsertOne(e); err != nil {
+		return nil, err
+	}
+	r := &Run{
+		entity:   e,
+		counters: make(map[string]map[string]map[string]int),
+	}
+	db.run = r
+	return r, nil
+}
+
+// FinishRun stores the end and counters of the given run, if err is not nil the run is marked as failed.
+func (db *Db) FinishRun(r *Run, err error) error {
+	if db.run == r {
+		db.run = nil
+	}
+	r.entity.Data["status"] = RunFinishedStatus
+	if err != nil {
+		r.entity.Data["status"] = RunFailedStatus
+		r.entity.Data["error"] = err.Error()
+	}
+	r.entity.Data["ended_at"] = time.Now()
+	r.entity.Data["counters"] = r.Counters()
+	return db.Driver.UpdateOne(r.entity)
+}
+
+// FlushRun stores the counters tracked so far by the given run while it is in progress.
+func (db *Db) FlushRun(r *Run) error {
+	return db.Driver.UpdateMany(RunTableName, map[string]any{"_id": r.Id()}, map[string]any{"counters": r.Counters()})
+}
+
+// CurrentRun returns the run started by StartRun, or nil if no run is in progress.
+func (db *Db) CurrentRun() *Run {
+	return db.run
+}
+
+// Clone returns a Db sharing the same Driver which tracks its own run, allowing runs to be started concurrently.
+func (db *Db) Clone() *Db {
+	return &Db{
+		db.Driver,
+		nil,
+	}
+}
+
+// StampRun sets the ID of the current run on the given Entity, the tables tracking runs and raw bodies are not stamped
+// as runs do not belong to themselves and raw bodies are shared between runs. Inserts are stamped automatically,
+// updates which replace a document's contents should stamp it explicitly.
+func (db *Db) StampRun(e *Entity) {
+	if db.run == nil || e.Table == RunTableName || e.Table == RawBodyTableName {
+		return
+	}
+	e.Data["run_id"] = db.run.Id()
+}
@@ -0,0 +1,39 @@
+package database
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestRun_Count(t *testing.T) {
+	r := &Run{
+		entity:   NewEntity(RunTableName, map[string]any{}),
+		counters: make(map[string]map[string]map[string]int),
+	}
+	wg := sync.WaitGroup{}
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.Count("burpee_html", "crawl", "crawled")
+			r.Count("burpee_html", "filter", "filtered")
+		}()
+	}
+	wg.Wait()
+	r.Count("burpee_html", "filter", "failed")
+	expected := map[string]any{
+		"burpee_html": map[string]any{
+			"crawl":  map[string]any{"crawled": 50},
+			"filter": map[string]any{"filtered": 50, "failed": 1},
+		},
+	}
+	if !reflect.DeepEqual(r.Counters(), expected) {
+		t.Errorf("Expected counters %v, got %v", expected, r.Counters())
+	}
+	var untracked *Run
+	untracked.Count("burpee_html", "crawl", "crawled")
+	if untracked.Counters() != nil || untracked.Id() != nil {
+		t.Errorf("Expected nil run to track nothing")
+	}
+}
@@ -3,15 +3,13 @@ package filter
 import (
 	"fmt"
 	"github.com/mmaaskant/gro-crop-scraper/helper"
-	"golang.org/x/net/html"
-	"log"
-	"reflect"
 	"regexp"
+	"strings"
 )
 
 // ConditionInterpreter functions as a Mediator for Condition, allowing the data to be typed and matched.
 type ConditionInterpreter interface {
-	Interpret(data any) bool
+	Interpret(data any) (bool, error)
 }
 
 // KeyValueInterpreter implements ConditionInterpreter and allows a key value pair in the format
@@ -21,18 +19,22 @@ type KeyValueInterpreter struct {
 }
 
 // Interpret implements ConditionInterpreter.Interpret.
-func (kvi *KeyValueInterpreter) Interpret(data any) bool {
+func (kvi *KeyValueInterpreter) Interpret(data any) (bool, error) {
 	var pair map[string]any
 	pair, ok := data.(map[string]any)
 	if !ok {
-		log.Panicf(formatInterpreterTypeErrorMessage(pair, data))
+		return false, NewTypeError(kvi, pair, data)
 	}
 	for k, v := range pair {
 		if !kvi.condition.MatchOne(&k, &v) {
-			return false
+			return false, nil
 		}
 	}
-	return true
+	return true, nil
+}
+
+func (kvi *KeyValueInterpreter) String() string {
+	return fmt.Sprintf("key_value[%s]", kvi.condition)
 }
 
 func NewKeyValueInterpreter(keyExpr string, valueExpr string) *KeyValueInterpreter {
@@ -48,13 +50,16 @@ type HtmlTokenTagInterpreter struct {
 }
 
 // Interpret implements ConditionInterpreter.Interpret.
-func (htti *HtmlTokenTagInterpreter) Interpret(data any) bool {
-	var token *html.Token
-	token, ok := data.(*html.Token)
+func (htti *HtmlTokenTagInterpreter) Interpret(data any) (bool, error) {
+	token, ok := htmlToken(data)
 	if !ok {
-		log.Panicf(formatInterpreterTypeErrorMessage(token, data))
+		return false, NewTypeError(htti, token, data)
 	}
-	return htti.condition.MatchOne(&token.Data, nil)
+	return htti.condition.MatchOne(&token.Data, nil), nil
+}
+
+func (htti *HtmlTokenTagInterpreter) String() string {
+	return fmt.Sprintf("tag[%s]", htti.condition)
 }
 
 func NewHtmlTokenTagInterpreter(expr string) *HtmlTokenTagInterpreter {
@@ -70,18 +75,21 @@ type HtmlTokenAttributeInterpreter struct {
 }
 
 // Interpret implements ConditionInterpreter.Interpret.
-func (htai *HtmlTokenAttributeInterpreter) Interpret(data any) bool {
-	var token *html.Token
-	token, ok := data.(*html.Token)
+func (htai *HtmlTokenAttributeInterpreter) Interpret(data any) (bool, error) {
+	token, ok := htmlToken(data)
 	if !ok {
-		log.Panicf(formatInterpreterTypeErrorMessage(token, data))
+		return false, NewTypeError(htai, token, data)
 	}
 	for _, attr := range token.Attr {
 		if htai.condition.MatchOne(&attr.Key, &attr.Val) {
-			return true
+			return true, nil
 		}
 	}
-	return false
+	return false, nil
+}
+
+func (htai *HtmlTokenAttributeInterpreter) String() string {
+	return fmt.Sprintf("attribute[%s]", htai.condition)
 }
 
 func NewHtmlTokenAttributeInterpreter(keyExpr string, valueExpr string) *HtmlTokenAttributeInterpreter {
@@ -90,8 +98,115 @@ func NewHtmlTokenAttributeInterpreter(keyExpr string, valueExpr string) *HtmlTok
 	}
 }
 
-func formatInterpreterTypeErrorMessage(expected any, got any) string {
-	return fmt.Sprintf("Interperter expected type %s, got: %s", reflect.TypeOf(expected), reflect.TypeOf(got))
+// HtmlTextInterpreter implements ConditionInterpreter and allows an instance of *HtmlElement to be parsed,
+// and checks if the element's inner text matches. As the inner text is found by reading ahead in the document,
+// it should be combined with a ConditionInterpreter that is cheaper to run, like HtmlTokenTagInterpreter.
+type HtmlTextInterpreter struct {
+	condition *Condition
+}
+
+// Interpret implements ConditionInterpreter.Interpret.
+func (hti *HtmlTextInterpreter) Interpret(data any) (bool, error) {
+	var element *HtmlElement
+	element, ok := data.(*HtmlElement)
+	if !ok {
+		return false, NewTypeError(hti, element, data)
+	}
+	text := element.Text()
+	return hti.condition.MatchOne(nil, &text), nil
+}
+
+func (hti *HtmlTextInterpreter) String() string {
+	return fmt.Sprintf("text[%s]", hti.condition.valueRegex)
+}
+
+func NewHtmlTextInterpreter(expr string) *HtmlTextInterpreter {
+	return &HtmlTextInterpreter{
+		NewCondition(nil, &expr),
+	}
+}
+
+// AnyInterpreter implements ConditionInterpreter and matches if any of its ConditionInterpreter instances match.
+type AnyInterpreter struct {
+	interpreters []ConditionInterpreter
+}
+
+// Interpret implements ConditionInterpreter.Interpret.
+func (ai *AnyInterpreter) Interpret(data any) (bool, error) {
+	for _, i := range ai.interpreters {
+		if ok, err := i.Interpret(data); ok || err != nil {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+func (ai *AnyInterpreter) String() string {
+	return fmt.Sprintf("any(%s)", describeInterpreters(ai.interpreters))
+}
+
+func NewAnyInterpreter(interpreters ...ConditionInterpreter) *AnyInterpreter {
+	return &AnyInterpreter{
+		interpreters,
+	}
+}
+
+// AllInterpreter implements ConditionInterpreter and matches if all of its ConditionInterpreter instances match,
+// it allows a group of ConditionInterpreter instances to be nested within AnyInterpreter or NotInterpreter.
+type AllInterpreter struct {
+	interpreters []ConditionInterpreter
+}
+
+// Interpret implements ConditionInterpreter.Interpret.
+func (ai *AllInterpreter) Interpret(data any) (bool, error) {
+	for _, i := range ai.interpreters {
+		if ok, err := i.Interpret(data); !ok || err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func (ai *AllInterpreter) String() string {
+	return fmt.Sprintf("all(%s)", describeInterpreters(ai.interpreters))
+}
+
+func NewAllInterpreter(interpreters ...ConditionInterpreter) *AllInterpreter {
+	return &AllInterpreter{
+		interpreters,
+	}
+}
+
+// NotInterpreter implements ConditionInterpreter and inverts the result of its ConditionInterpreter.
+type NotInterpreter struct {
+	interpreter ConditionInterpreter
+}
+
+// Interpret implements ConditionInterpreter.Interpret.
+func (ni *NotInterpreter) Interpret(data any) (bool, error) {
+	ok, err := ni.interpreter.Interpret(data)
+	if err != nil {
+		return false, err
+	}
+	return !ok, nil
+}
+
+func (ni *NotInterpreter) String() string {
+	return fmt.Sprintf("not(%s)", ni.interpreter)
+}
+
+func NewNotInterpreter(interpreter ConditionInterpreter) *NotInterpreter {
+	return &NotInterpreter{
+		interpreter,
+	}
+}
+
+func describeInterpreters(interpreters []ConditionInterpreter) string {
+	descriptions := make([]string, 0)
+	for _, i := range interpreters {
+		descriptions = append(descriptions, fmt.Sprint(i))
+	}
+	return strings.Join(descriptions, ", ")
 }
 
 // Condition holds an optional key and value regex and determines if a value passes its requirements or not.
@@ -107,6 +222,18 @@ func NewCondition(keyExpr *string, valueExpr *string) *Condition {
 	}
 }
 
+// String describes the Condition by its regexes in the format "key=value", omitting any regex that was not provided.
+func (c *Condition) String() string {
+	var key, value string
+	if c.keyRegex != nil {
+		key = c.keyRegex.String()
+	}
+	if c.valueRegex != nil {
+		value = "=" + c.valueRegex.String()
+	}
+	return key + value
+}
+
 func (c *Condition) MatchOne(key *string, value any) bool {
 	if c.keyRegex != nil && key != nil && !c.keyRegex.MatchString(*key) {
 		return false
@@ -1,6 +1,12 @@
 package filter
 
-// CriteriaBuilder simplifies the adding of children to a Criteria.
+import (
+	"fmt"
+	"strings"
+)
+
+// CriteriaBuilder simplifies the building of a chain of Criteria, in which each added Criteria
+// is either a child or a sibling of the previously added Criteria.
 type CriteriaBuilder struct {
 	criteria *Criteria
 }
@@ -11,67 +17,151 @@ func NewCriteriaBuilder(c *Criteria) *CriteriaBuilder {
 	}
 }
 
+// AddChild adds a child to the last added Criteria, and continues building from the child onwards.
 func (cb *CriteriaBuilder) AddChild(child *Criteria) *CriteriaBuilder {
-	child.Parent = cb.criteria
-	cb.criteria.Child = child
+	cb.criteria.AddChildren(child)
 	cb.criteria = child
 	return cb
 }
 
+// AddSibling adds a sibling to the last added Criteria, and continues building from the sibling onwards.
+func (cb *CriteriaBuilder) AddSibling(sibling *Criteria) *CriteriaBuilder {
+	cb.criteria.AddSiblings(sibling)
+	cb.criteria = sibling
+	return cb
+}
+
+// Build returns the root Criteria.
 func (cb *CriteriaBuilder) Build() *Criteria {
-	criteria := cb.criteria
-	for parent := criteria.Parent; parent != nil; parent = parent.Parent {
-		criteria = parent
-	}
-	return criteria
+	return cb.criteria.Root()
 }
 
 // Criteria defines if a set of data passes its requirements or not, and can optionally extract the matched data
-// using Extractor.
+// using Extractor. Criteria form a tree, in which Children are matched against data nested within the matched data
+// and Siblings are matched against the data directly following the matched data.
 type Criteria struct {
 	Extractor    Extractor
 	interpreters []ConditionInterpreter
-	Depth        int
 	Parent       *Criteria
-	Child        *Criteria
+	Previous     *Criteria
+	Children     []*Criteria
+	Siblings     []*Criteria
 }
 
 func NewCriteria(extractor Extractor, interpreters ...ConditionInterpreter) *Criteria {
 	return &Criteria{
 		extractor,
 		interpreters,
-		0,
 		nil,
 		nil,
+		make([]*Criteria, 0),
+		make([]*Criteria, 0),
 	}
 }
 
-func (c *Criteria) Match(data any) bool {
+// AddChildren adds Criteria that are matched against any data nested within the data matched by this Criteria.
+func (c *Criteria) AddChildren(children ...*Criteria) *Criteria {
+	for _, child := range children {
+		child.Parent = c
+		c.Children = append(c.Children, child)
+	}
+	return c
+}
+
+// AddSiblings adds Criteria that are matched against the data that directly follows the data matched by this Criteria,
+// e.g. the element right after a matched <h3> element.
+func (c *Criteria) AddSiblings(siblings ...*Criteria) *Criteria {
+	for _, sibling := range siblings {
+		sibling.Previous = c
+		c.Siblings = append(c.Siblings, sibling)
+	}
+	return c
+}
+
+// Match checks if all ConditionInterpreter instances match the given data,
+// any error returned by a ConditionInterpreter is wrapped in a CriteriaError.
+func (c *Criteria) Match(data any) (bool, error) {
 	for _, i := range c.interpreters {
-		if !i.Interpret(data) {
-			return false
+		ok, err := i.Interpret(data)
+		if err != nil {
+			return false, newCriteriaError(c, err)
+		}
+		if !ok {
+			return false, nil
 		}
 	}
-	return true
+	return true, nil
 }
 
-func (c *Criteria) Previous() *Criteria {
-	return c.Parent
+// Extract runs the Criteria's Extractor on the given data,
+// any error returned by the Extractor is wrapped in a CriteriaError.
+func (c *Criteria) Extract(data any) (map[string]any, error) {
+	if c.Extractor == nil {
+		return nil, nil
+	}
+	extractedData, err := c.Extractor.Extract(data)
+	if err != nil {
+		return nil, newCriteriaError(c, err)
+	}
+	return extractedData, nil
+}
+
+// Root returns the Criteria at the root of the tree this Criteria belongs to.
+func (c *Criteria) Root() *Criteria {
+	root := c
+	for {
+		switch {
+		case root.Previous != nil:
+			root = root.Previous
+		case root.Parent != nil:
+			root = root.Parent
+		default:
+			return root
+		}
+	}
 }
 
-func (c *Criteria) Next() *Criteria {
-	return c.Child
+// String describes the Criteria by its ConditionInterpreter instances, e.g. "tag[div] attribute[class=product]".
+func (c *Criteria) String() string {
+	descriptions := make([]string, 0)
+	for _, i := range c.interpreters {
+		descriptions = append(descriptions, fmt.Sprint(i))
+	}
+	return strings.Join(descriptions, " ")
 }
 
-func (c *Criteria) Clone() *Criteria {
-	cCopy := *c
-	for child := cCopy.Next(); child != nil; child = child.Next() {
-		parentCopy := *child.Parent
-		child.Parent = &parentCopy
-		if child.Child != nil {
-			childCopy := *child.Child
-			child.Child = &childCopy
+// Describe describes the Criteria, its Extractor and all of its children and siblings,
+// e.g. "tag[div] > (tag[h1] => text[name]) + (tag[p])".
+func (c *Criteria) Describe() string {
+	description := c.String()
+	if c.Extractor != nil {
+		description += " => " + fmt.Sprint(c.Extractor)
+	}
+	for _, related := range []struct {
+		separator string
+		criteria  []*Criteria
+	}{{" > ", c.Children}, {" + ", c.Siblings}} {
+		if len(related.criteria) == 0 {
+			continue
+		}
+		descriptions := make([]string, 0)
+		for _, r := range related.criteria {
+			descriptions = append(descriptions, r.Describe())
 		}
+		description += related.separator + "(" + strings.Join(descriptions, "; ") + ")"
+	}
+	return description
+}
+
+// Path describes the Criteria and the Criteria leading up to it starting at the root Criteria,
+// children are separated by " > " and siblings by " + ".
+func (c *Criteria) Path() string {
+	switch {
+	case c.Previous != nil:
+		return c.Previous.Path() + " + " + c.String()
+	case c.Parent != nil:
+		return c.Parent.Path() + " > " + c.String()
+	default:
+		return c.String()
 	}
-	return &cCopy
 }
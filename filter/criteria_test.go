@@ -0,0 +1,153 @@
+package filter
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+const testProductHtml = `<html><body>
+<div class="product">
+	<h1 class="title">Tomato, Sungold Hybrid</h1>
+	<img src="tomato.jpg" alt="Sungold">
+	<div class="details">
+		<h3>Days to Maturity</h3>
+		<p>57-65 days</p>
+		<h3>Sun</h3>
+		<p>Full Sun</p>
+		<h4>Notes</h4>
+		<p>Indeterminate</p>
+	</div>
+</div>
+<div class="related">
+	<h1 class="title">Tomato, Brandywine</h1>
+</div>
+</body></html>`
+
+func TestHtmlFilter_CriteriaTree(t *testing.T) {
+	product := NewCriteria(nil, NewHtmlTokenTagInterpreter("div"), NewHtmlTokenAttributeInterpreter("class", "^product$"))
+	product.AddChildren(
+		NewCriteria(NewHtmlTextExtractor("name"), NewHtmlTokenTagInterpreter("h1")),
+		NewCriteria(NewHtmlAttributeExtractor("src|alt"), NewHtmlTokenTagInterpreter("img")),
+		NewCriteria(nil, NewHtmlTokenTagInterpreter("div")).AddChildren(
+			NewCriteria(nil, NewHtmlTokenTagInterpreter("h3")).AddSiblings(
+				NewCriteria(NewHtmlTextExtractor("h3_sibling"), NewHtmlTokenTagInterpreter("p")),
+			),
+		),
+	)
+	data, err := NewHtmlFilter(product).Clone().Filter(testProductHtml)
+	if err != nil {
+		t.Fatalf("Failed to filter, error: %s", err)
+	}
+	expected := map[string]any{
+		"name":       "Tomato, Sungold Hybrid",
+		"src":        "tomato.jpg",
+		"alt":        "Sungold",
+		"h3_sibling": "Full Sun",
+	}
+	if !reflect.DeepEqual(data, expected) {
+		t.Errorf("Got %v, expected: %v", data, expected)
+	}
+}
+
+func TestHtmlFilter_SiblingOnlyMatchesNextElement(t *testing.T) {
+	cb := NewCriteriaBuilder(NewCriteria(nil, NewHtmlTokenTagInterpreter("h4")))
+	cb.AddSibling(NewCriteria(NewHtmlTextExtractor("notes"), NewHtmlTokenTagInterpreter("p")))
+	cb.AddSibling(NewCriteria(NewHtmlTextExtractor("unexpected"), NewHtmlTokenTagInterpreter("p")))
+	data, err := NewHtmlFilter(cb.Build()).Filter(testProductHtml)
+	if err != nil {
+		t.Fatalf("Failed to filter, error: %s", err)
+	}
+	expected := map[string]any{"notes": "Indeterminate"}
+	if !reflect.DeepEqual(data, expected) {
+		t.Errorf("Got %v, expected: %v", data, expected)
+	}
+}
+
+func TestHtmlFilter_BooleanInterpreters(t *testing.T) {
+	c := NewCriteria(
+		NewHtmlTextExtractor("heading"),
+		NewAnyInterpreter(NewHtmlTokenTagInterpreter("^h3$"), NewHtmlTokenTagInterpreter("^h4$")),
+		NewNotInterpreter(NewAllInterpreter(NewHtmlTokenTagInterpreter("^h3$"))),
+	)
+	data, err := NewHtmlFilter(c).Filter(testProductHtml)
+	if err != nil {
+		t.Fatalf("Failed to filter, error: %s", err)
+	}
+	expected := map[string]any{"heading": "Notes"}
+	if !reflect.DeepEqual(data, expected) {
+		t.Errorf("Got %v, expected: %v", data, expected)
+	}
+}
+
+func TestJsonFilter_CriteriaTree(t *testing.T) {
+	c := NewCriteria(nil, NewKeyValueInterpreter("^product$", ""))
+	c.AddChildren(NewCriteria(NewKeyValueExtractor("", ""), NewKeyValueInterpreter("^(name|sku)$", "")))
+	c.AddSiblings(NewCriteria(NewKeyValueExtractor("", ""), NewKeyValueInterpreter("^currency$", "")))
+	data, err := NewJsonFilter(c).Filter(`{
+		"product": {"name": "Tomato, Sungold Hybrid", "sku": "prod001234"},
+		"currency": "USD",
+		"related": {"name": "Tomato, Brandywine", "currency": "EUR"}
+	}`)
+	if err != nil {
+		t.Fatalf("Failed to filter, error: %s", err)
+	}
+	expected := map[string]any{
+		"name":     "Tomato, Sungold Hybrid",
+		"sku":      "prod001234",
+		"currency": "USD",
+	}
+	if !reflect.DeepEqual(data, expected) {
+		t.Errorf("Got %v, expected: %v", data, expected)
+	}
+}
+
+func TestFilter_ReturnsTypeErrors(t *testing.T) {
+	cases := map[string]struct {
+		filter Filter
+		data   string
+	}{
+		"interpreter": {
+			NewJsonFilter(NewCriteria(nil, NewHtmlTokenTagInterpreter("div"))),
+			`{"div": "Tomato, Sungold Hybrid"}`,
+		},
+		"extractor": {
+			NewHtmlFilter(NewCriteria(NewKeyValueExtractor("", ""), NewHtmlTokenTagInterpreter("h1"))),
+			testProductHtml,
+		},
+	}
+	for name, c := range cases {
+		data, err := c.filter.Filter(c.data)
+		var te *TypeError
+		if !errors.As(err, &te) {
+			t.Errorf("%s: expected a TypeError, got: %v", name, err)
+		}
+		var ce *CriteriaError
+		if !errors.As(err, &ce) || ce.Criteria == "" {
+			t.Errorf("%s: expected a CriteriaError describing the criteria, got: %v", name, err)
+		}
+		if data != nil {
+			t.Errorf("%s: expected no data, got: %v", name, data)
+		}
+	}
+}
+
+func TestTracker_Version(t *testing.T) {
+	newFilter := func(alias string) *HtmlFilter {
+		c := NewCriteria(nil, NewHtmlTokenTagInterpreter("div"))
+		c.AddChildren(NewCriteria(NewHtmlTableExtractor(map[string]string{"Sun": alias}), NewHtmlTokenTagInterpreter("table")))
+		return NewHtmlFilter(c)
+	}
+	version := newFilter("sun_requirement").Version()
+	if newFilter("sun_requirement").Clone().Version() != version {
+		t.Errorf("Expected identical filters to have the same version")
+	}
+	if newFilter("sun").Version() == version {
+		t.Errorf("Expected a changed extractor to change the version")
+	}
+	revised := newFilter("sun_requirement")
+	revised.SetRevision("2")
+	if revised.Version() == version {
+		t.Errorf("Expected a changed revision to change the version")
+	}
+}
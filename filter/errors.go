@@ -0,0 +1,58 @@
+package filter
+
+import (
+	"fmt"
+	"reflect"
+	"runtime/debug"
+)
+
+// TypeError is returned by a ConditionInterpreter or Extractor that received data of a type it can not handle,
+// it holds the stack at the time it occurred so it can be traced back once recorded.
+type TypeError struct {
+	Component string
+	Expected  reflect.Type
+	Got       reflect.Type
+	stack     []byte
+}
+
+// NewTypeError returns a TypeError for the given component, e.g. the Extractor or the Clean function's owner,
+// which expected a value of the type of expected but got the given value.
+func NewTypeError(component any, expected any, got any) *TypeError {
+	return &TypeError{
+		reflect.TypeOf(component).String(),
+		reflect.TypeOf(expected),
+		reflect.TypeOf(got),
+		debug.Stack(),
+	}
+}
+
+func (te *TypeError) Error() string {
+	return fmt.Sprintf("%s expected type %s, got: %s", te.Component, te.Expected, te.Got)
+}
+
+// Stack returns the stack at the time TypeError occurred.
+func (te *TypeError) Stack() []byte {
+	return te.stack
+}
+
+// CriteriaError wraps an error returned while matching or extracting data using Criteria,
+// and describes the Criteria that caused it using Criteria.Path.
+type CriteriaError struct {
+	Criteria string
+	Err      error
+}
+
+func newCriteriaError(c *Criteria, err error) *CriteriaError {
+	return &CriteriaError{
+		c.Path(),
+		err,
+	}
+}
+
+func (ce *CriteriaError) Error() string {
+	return fmt.Sprintf("criteria %s failed, error: %s", ce.Criteria, ce.Err)
+}
+
+func (ce *CriteriaError) Unwrap() error {
+	return ce.Err
+}
@@ -3,46 +3,49 @@ package filter
 import (
 	"fmt"
 	"github.com/mmaaskant/gro-crop-scraper/helper"
+	"github.com/mmaaskant/gro-crop-scraper/logger"
 	"golang.org/x/net/html"
-	"log"
-	"reflect"
+	"golang.org/x/net/html/atom"
 	"regexp"
+	"sort"
+	"strings"
 )
 
 // Extractor attempts to map, organise and extract data and return it.
 type Extractor interface {
-	Extract(data any) map[string]any
+	Extract(data any) (map[string]any, error)
 }
 
 // KeyValueExtractor implements Extractor and extracts data based on the provided key and value regexes.
-// An optional Clean function can be provided which is run on the results found before they are returned.
+// An optional Clean function can be provided which is run on the results found before they are returned,
+// any error it returns is returned by Extract.
 type KeyValueExtractor struct {
 	keyRegex   *regexp.Regexp
 	valueRegex *regexp.Regexp
-	Clean      *func(data map[string]any) map[string]any
+	Clean      *func(data map[string]any) (map[string]any, error)
 }
 
-func (kve *KeyValueExtractor) Extract(data any) map[string]any {
+func (kve *KeyValueExtractor) Extract(data any) (map[string]any, error) {
 	var pair map[string]any
 	pair, ok := data.(map[string]any)
 	if !ok {
-		log.Panicf(formatExtractorTypeErrorMessage(pair, data))
+		return nil, NewTypeError(kve, pair, data)
 	}
 	for k, v := range pair {
 		if kve.keyRegex != nil {
 			if !kve.keyRegex.MatchString(k) {
-				return nil
+				return nil, nil
 			}
 		}
 		if kve.valueRegex != nil {
 			switch value := v.(type) {
 			case string:
 				if !kve.valueRegex.MatchString(value) {
-					return nil
+					return nil, nil
 				}
 			case *string:
 				if !kve.valueRegex.MatchString(*value) {
-					return nil
+					return nil, nil
 				}
 			case []any:
 				values := make([]string, 0)
@@ -53,7 +56,7 @@ func (kve *KeyValueExtractor) Extract(data any) map[string]any {
 					}
 				}
 				if len(values) == 0 {
-					return nil
+					return nil, nil
 				}
 				pair = map[string]any{k: values}
 			}
@@ -61,13 +64,20 @@ func (kve *KeyValueExtractor) Extract(data any) map[string]any {
 
 	}
 	if kve.Clean != nil {
-		pair = (*kve.Clean)(pair)
+		var err error
+		if pair, err = (*kve.Clean)(pair); err != nil {
+			return nil, err
+		}
 	}
-	return pair
+	return pair, nil
+}
+
+func (kve *KeyValueExtractor) String() string {
+	return fmt.Sprintf("key_value[%s]%s", (&Condition{kve.keyRegex, kve.valueRegex}).String(), describeClean(kve.Clean))
 }
 
-func NewKeyValueExtractor(keyExpr string, valueExpr string, clean ...func(data map[string]any) map[string]any) *KeyValueExtractor {
-	var f *func(data map[string]any) map[string]any
+func NewKeyValueExtractor(keyExpr string, valueExpr string, clean ...func(data map[string]any) (map[string]any, error)) *KeyValueExtractor {
+	var f *func(data map[string]any) (map[string]any, error)
 	if len(clean) > 0 {
 		f = &clean[0]
 	}
@@ -79,27 +89,34 @@ func NewKeyValueExtractor(keyExpr string, valueExpr string, clean ...func(data m
 }
 
 // HtmlTextExtractor implements Extractor and extracts text from the *html.Token.
-// An optional Clean function can be provided which is run on the results found before they are returned.
+// An optional Clean function can be provided which is run on the results found before they are returned,
+// any error it returns is returned by Extract.
 type HtmlTextExtractor struct {
 	id    string
-	Clean *func(data map[string]any) map[string]any
+	Clean *func(data map[string]any) (map[string]any, error)
 }
 
-func (hte *HtmlTextExtractor) Extract(data any) map[string]any {
-	var token *html.Token
-	token, ok := data.(*html.Token)
+func (hte *HtmlTextExtractor) Extract(data any) (map[string]any, error) {
+	token, ok := htmlToken(data)
 	if !ok {
-		log.Panicf(formatExtractorTypeErrorMessage(token, data))
+		return nil, NewTypeError(hte, token, data)
 	}
 	text := map[string]any{hte.id: token.Data}
 	if hte.Clean != nil {
-		text = (*hte.Clean)(text)
+		var err error
+		if text, err = (*hte.Clean)(text); err != nil {
+			return nil, err
+		}
 	}
-	return text
+	return text, nil
+}
+
+func (hte *HtmlTextExtractor) String() string {
+	return fmt.Sprintf("text[%s]%s", hte.id, describeClean(hte.Clean))
 }
 
-func NewHtmlTextExtractor(id string, clean ...func(data map[string]any) map[string]any) *HtmlTextExtractor {
-	var f *func(data map[string]any) map[string]any
+func NewHtmlTextExtractor(id string, clean ...func(data map[string]any) (map[string]any, error)) *HtmlTextExtractor {
+	var f *func(data map[string]any) (map[string]any, error)
 	if len(clean) > 0 {
 		f = &clean[0]
 	}
@@ -110,17 +127,17 @@ func NewHtmlTextExtractor(id string, clean ...func(data map[string]any) map[stri
 }
 
 // HtmlAttributeExtractor implements Extractor and extracts attributes from the given *html.Token.
-// An optional Clean function can be provided which is run on the results found before they are returned.
+// An optional Clean function can be provided which is run on the results found before they are returned,
+// any error it returns is returned by Extract.
 type HtmlAttributeExtractor struct {
 	keyRegex *regexp.Regexp
-	Clean    *func(data map[string]any) map[string]any
+	Clean    *func(data map[string]any) (map[string]any, error)
 }
 
-func (hae *HtmlAttributeExtractor) Extract(data any) map[string]any {
-	var token *html.Token
-	token, ok := data.(*html.Token)
+func (hae *HtmlAttributeExtractor) Extract(data any) (map[string]any, error) {
+	token, ok := htmlToken(data)
 	if !ok {
-		log.Panicf(formatExtractorTypeErrorMessage(token, data))
+		return nil, NewTypeError(hae, token, data)
 	}
 	attributes := make(map[string]any)
 	for _, attr := range token.Attr {
@@ -129,13 +146,20 @@ func (hae *HtmlAttributeExtractor) Extract(data any) map[string]any {
 		}
 	}
 	if hae.Clean != nil {
-		attributes = (*hae.Clean)(attributes)
+		var err error
+		if attributes, err = (*hae.Clean)(attributes); err != nil {
+			return nil, err
+		}
 	}
-	return attributes
+	return attributes, nil
+}
+
+func (hae *HtmlAttributeExtractor) String() string {
+	return fmt.Sprintf("attribute[%s]%s", hae.keyRegex, describeClean(hae.Clean))
 }
 
-func NewHtmlAttributeExtractor(keyExpr string, clean ...func(map[string]any) map[string]any) *HtmlAttributeExtractor {
-	var f *func(data map[string]any) map[string]any
+func NewHtmlAttributeExtractor(keyExpr string, clean ...func(data map[string]any) (map[string]any, error)) *HtmlAttributeExtractor {
+	var f *func(data map[string]any) (map[string]any, error)
 	if len(clean) > 0 {
 		f = &clean[0]
 	}
@@ -145,6 +169,228 @@ func NewHtmlAttributeExtractor(keyExpr string, clean ...func(map[string]any) map
 	}
 }
 
-func formatExtractorTypeErrorMessage(expected any, got any) string {
-	return fmt.Sprintf("Extractor expected type %s, got: %s", reflect.TypeOf(expected), reflect.TypeOf(got))
+// RegexCaptureExtractor implements Extractor and extracts the named capture groups of its regex from text,
+// e.g. `(?P<days_to_maturity>\d+)-\d+ days` extracts "65" out of "65-75 days" and stores it under "days_to_maturity".
+// Within HtmlFilter the text of the entire matched element is used, within JsonFilter the matched value is used.
+// An optional Clean function can be provided which is run on the results found before they are returned,
+// any error it returns is returned by Extract.
+type RegexCaptureExtractor struct {
+	regex *regexp.Regexp
+	Clean *func(data map[string]any) (map[string]any, error)
+}
+
+func (rce *RegexCaptureExtractor) Extract(data any) (map[string]any, error) {
+	texts := make([]string, 0)
+	switch d := data.(type) {
+	case string:
+		texts = append(texts, d)
+	case *html.Token:
+		texts = append(texts, d.Data)
+	case *HtmlElement:
+		texts = append(texts, d.Text())
+	case map[string]any:
+		for _, v := range d {
+			switch value := v.(type) {
+			case string:
+				texts = append(texts, value)
+			case []any:
+				for _, listItem := range value {
+					texts = append(texts, fmt.Sprint(listItem))
+				}
+			}
+		}
+	default:
+		return nil, NewTypeError(rce, "", data)
+	}
+	captures := make(map[string]any)
+	for _, text := range texts {
+		match := rce.regex.FindStringSubmatch(text)
+		if match == nil {
+			continue
+		}
+		for i, name := range rce.regex.SubexpNames() {
+			if _, ok := captures[name]; name != "" && match[i] != "" && !ok {
+				captures[name] = match[i]
+			}
+		}
+	}
+	if len(captures) == 0 {
+		return nil, nil
+	}
+	if rce.Clean != nil {
+		var err error
+		if captures, err = (*rce.Clean)(captures); err != nil {
+			return nil, err
+		}
+	}
+	return captures, nil
+}
+
+func (rce *RegexCaptureExtractor) String() string {
+	return fmt.Sprintf("regex_capture[%s]%s", rce.regex, describeClean(rce.Clean))
+}
+
+// NewRegexCaptureExtractor returns a new RegexCaptureExtractor and panics if the regex does not hold any named capture groups.
+func NewRegexCaptureExtractor(expr string, clean ...func(data map[string]any) (map[string]any, error)) *RegexCaptureExtractor {
+	var f *func(data map[string]any) (map[string]any, error)
+	if len(clean) > 0 {
+		f = &clean[0]
+	}
+	regex := helper.CompileRegex(&expr)
+	if regex == nil || !helper.HasNamedGroup(regex) {
+		logger.Panicf("Regex capture extractor expected regex %s to hold at least one named capture group", expr)
+	}
+	return &RegexCaptureExtractor{
+		regex,
+		f,
+	}
+}
+
+// HtmlTableExtractor implements Extractor and extracts label value pairs from the rows of a matched
+// <table>, <dl>, <ul> or <ol> element. It expects the element's markup as a string, which HtmlFilter collects
+// until the element is closed. Labels are normalised to snake_case and optionally renamed using aliases.
+// An optional Clean function can be provided which is run on the results found before they are returned,
+// any error it returns is returned by Extract.
+type HtmlTableExtractor struct {
+	aliases map[string]string
+	Clean   *func(data map[string]any) (map[string]any, error)
+}
+
+func (hte *HtmlTableExtractor) Extract(data any) (map[string]any, error) {
+	var markup string
+	markup, ok := data.(string)
+	if !ok {
+		return nil, NewTypeError(hte, markup, data)
+	}
+	node, err := html.Parse(strings.NewReader(markup))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HTML table, error: %w", err)
+	}
+	rows := make(map[string]any)
+	hte.walk(node, rows)
+	if hte.Clean != nil {
+		if rows, err = (*hte.Clean)(rows); err != nil {
+			return nil, err
+		}
+	}
+	return rows, nil
+}
+
+// walk recursively searches the given *html.Node for table rows, definition list pairs and list items.
+func (hte *HtmlTableExtractor) walk(n *html.Node, rows map[string]any) {
+	if n.Type == html.ElementNode {
+		switch n.DataAtom {
+		case atom.Tr:
+			cells := make([]string, 0)
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				if c.Type == html.ElementNode && (c.DataAtom == atom.Th || c.DataAtom == atom.Td) {
+					cells = append(cells, nodeText(c))
+				}
+			}
+			if len(cells) > 1 {
+				hte.add(rows, cells[0], strings.Join(cells[1:], " "))
+			}
+			return
+		case atom.Dl:
+			var label string
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				if c.Type != html.ElementNode {
+					continue
+				}
+				switch c.DataAtom {
+				case atom.Dt:
+					label = nodeText(c)
+				case atom.Dd:
+					hte.add(rows, label, nodeText(c))
+				}
+			}
+			return
+		case atom.Li:
+			if label, value, found := strings.Cut(nodeText(n), ":"); found {
+				hte.add(rows, label, strings.TrimSpace(value))
+			}
+			return
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		hte.walk(c, rows)
+	}
+}
+
+// add normalises the given label and stores the value, a label that occurs more than once holds a slice of values.
+func (hte *HtmlTableExtractor) add(rows map[string]any, label string, value string) {
+	key := NormaliseLabel(label)
+	if key == "" || value == "" {
+		return
+	}
+	if alias, ok := hte.aliases[key]; ok {
+		key = alias
+	}
+	switch existing := rows[key].(type) {
+	case nil:
+		rows[key] = value
+	case string:
+		rows[key] = []string{existing, value}
+	case []string:
+		rows[key] = append(existing, value)
+	}
+}
+
+func (hte *HtmlTableExtractor) String() string {
+	aliases := make([]string, 0)
+	for label, alias := range hte.aliases {
+		aliases = append(aliases, label+"="+alias)
+	}
+	sort.Strings(aliases)
+	return fmt.Sprintf("table[%s]%s", strings.Join(aliases, ", "), describeClean(hte.Clean))
+}
+
+// NewHtmlTableExtractor returns a new HtmlTableExtractor, aliases map normalised labels to the key they are stored under.
+func NewHtmlTableExtractor(aliases map[string]string, clean ...func(data map[string]any) (map[string]any, error)) *HtmlTableExtractor {
+	var f *func(data map[string]any) (map[string]any, error)
+	if len(clean) > 0 {
+		f = &clean[0]
+	}
+	normalisedAliases := make(map[string]string)
+	for label, alias := range aliases {
+		normalisedAliases[NormaliseLabel(label)] = alias
+	}
+	return &HtmlTableExtractor{
+		normalisedAliases,
+		f,
+	}
+}
+
+var labelSeparatorRegex = regexp.MustCompile(`[^a-z0-9]+`)
+
+// NormaliseLabel trims and lowercases the given label and converts it to snake_case, "Days to Maturity:" becomes "days_to_maturity".
+func NormaliseLabel(label string) string {
+	label = labelSeparatorRegex.ReplaceAllString(strings.ToLower(strings.TrimSpace(label)), "_")
+	return strings.Trim(label, "_")
+}
+
+// nodeText returns all text held by the given *html.Node and its children with collapsed whitespace.
+func nodeText(n *html.Node) string {
+	var sb strings.Builder
+	var collect func(n *html.Node)
+	collect = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			sb.WriteString(n.Data)
+			sb.WriteString(" ")
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			collect(c)
+		}
+	}
+	collect(n)
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
+
+// describeClean marks an Extractor description with " clean" if it has a Clean function,
+// as functions can not be described any further.
+func describeClean(clean *func(data map[string]any) (map[string]any, error)) string {
+	if clean == nil {
+		return ""
+	}
+	return " clean"
 }
@@ -0,0 +1,98 @@
+package filter
+
+import (
+	"reflect"
+	"testing"
+)
+
+const testSpecificationHtml = `<html><body>
+<div class="product-specs">
+	<table class="specs">
+		<tr><th>Days to Maturity:</th><td>65-75 days</td></tr>
+		<tr><th>Sun</th><td>Full Sun</td></tr>
+		<tr><td>Spacing</td><td><span>18</span> inches</td></tr>
+	</table>
+	<dl class="details">
+		<dt>Mature Height</dt><dd>24"</dd>
+		<dt>Growing Zones</dt><dd>3</dd><dd>4</dd>
+	</dl>
+	<ul class="features">
+		<li>Fruit Size: 2 lbs</li>
+		<li>Heirloom</li>
+	</ul>
+</div>
+</body></html>`
+
+func TestHtmlTableExtractor_Extract(t *testing.T) {
+	cases := map[string]struct {
+		criteria *Criteria
+		expected map[string]any
+	}{
+		"table": {
+			NewCriteria(
+				NewHtmlTableExtractor(map[string]string{"Sun": "sun_requirement"}),
+				NewHtmlTokenTagInterpreter("table"),
+			),
+			map[string]any{
+				"days_to_maturity": "65-75 days",
+				"sun_requirement":  "Full Sun",
+				"spacing":          "18 inches",
+			},
+		},
+		"definition list": {
+			NewCriteria(
+				NewHtmlTableExtractor(nil),
+				NewHtmlTokenTagInterpreter("dl"),
+			),
+			map[string]any{
+				"mature_height": `24"`,
+				"growing_zones": []string{"3", "4"},
+			},
+		},
+		"unordered list": {
+			NewCriteria(
+				NewHtmlTableExtractor(nil),
+				NewHtmlTokenTagInterpreter("ul"),
+				NewHtmlTokenAttributeInterpreter("class", "features"),
+			),
+			map[string]any{
+				"fruit_size": "2 lbs",
+			},
+		},
+	}
+	for name, c := range cases {
+		data, err := NewHtmlFilter(c.criteria).Clone().Filter(testSpecificationHtml)
+		if err != nil {
+			t.Fatalf("%s: failed to filter, error: %s", name, err)
+		}
+		if !reflect.DeepEqual(data, c.expected) {
+			t.Errorf("%s: got %v, expected: %v", name, data, c.expected)
+		}
+	}
+}
+
+func TestNormaliseLabel(t *testing.T) {
+	for label, expected := range map[string]string{
+		"Days to Maturity:": "days_to_maturity",
+		"  Sun / Shade ":    "sun_shade",
+		"Height (inches)":   "height_inches",
+	} {
+		if got := NormaliseLabel(label); got != expected {
+			t.Errorf("Got label %s, expected: %s", got, expected)
+		}
+	}
+}
+
+func TestRegexCaptureExtractor_Extract(t *testing.T) {
+	extractor := NewRegexCaptureExtractor(`(?P<days_to_maturity_min>\d+)-(?P<days_to_maturity_max>\d+) days`)
+	expected := map[string]any{"days_to_maturity_min": "57", "days_to_maturity_max": "65"}
+	htmlCriteria := NewCriteria(nil, NewHtmlTokenTagInterpreter("h3"), NewHtmlTextInterpreter("^Days to Maturity$"))
+	htmlCriteria.AddSiblings(NewCriteria(extractor, NewHtmlTokenTagInterpreter("p")))
+	if data, err := NewHtmlFilter(htmlCriteria).Filter(testProductHtml); err != nil || !reflect.DeepEqual(data, expected) {
+		t.Errorf("HTML filter got %v, error: %v, expected: %v", data, err, expected)
+	}
+	jsonCriteria := NewCriteria(extractor, NewKeyValueInterpreter("^bp_days_to_maturity$", ""))
+	if data, err := NewJsonFilter(jsonCriteria).Filter(`{"bp_days_to_maturity": "57-65 days"}`); err != nil || !reflect.DeepEqual(data, expected) {
+		t.Errorf("JSON filter got %v, error: %v, expected: %v", data, err, expected)
+	}
+}
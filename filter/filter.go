@@ -1,26 +1,34 @@
 package filter
 
-import "github.com/mmaaskant/gro-crop-scraper/attribute"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/mmaaskant/gro-crop-scraper/attribute"
+)
 
 // Filter defines the ability to filter a type of data potentially extract any desired results using Criteria.
 type Filter interface {
 	attribute.Taggable
 	Clone() Filter
-	Filter(s string) map[string]any
+	Filter(s string) (map[string]any, error)
+	// Version returns a hash that changes whenever the Filter's Criteria change.
+	Version() string
 }
 
-// Tracker is used to track and manage a Filter's Criteria.
+// Tracker holds a Filter's root Criteria and reports any Criteria that match to its Tracer.
 type Tracker struct {
 	*attribute.Tag
-	criteria        []*Criteria
-	trackedCriteria map[*Criteria]bool
+	criteria []*Criteria
+	tracer   Tracer
+	revision string
 }
 
 func NewFilterTracker(criteria []*Criteria) *Tracker {
 	return &Tracker{
 		nil,
 		criteria,
-		make(map[*Criteria]bool),
+		nil,
+		"",
 	}
 }
 
@@ -28,9 +36,31 @@ func (tr *Tracker) SetTag(t *attribute.Tag) {
 	tr.Tag = t
 }
 
-func (tr *Tracker) getAllCriteria() map[*Criteria]bool {
+// SetTracer implements Traceable.SetTracer.
+func (tr *Tracker) SetTracer(t Tracer) {
+	tr.tracer = t
+}
+
+// SetRevision sets a revision which is included in the Tracker's Version, as Clean functions can not be described
+// the revision should be changed whenever a Clean function used by its Criteria changes.
+func (tr *Tracker) SetRevision(revision string) {
+	tr.revision = revision
+}
+
+// Version implements Filter.Version and hashes the revision along with the description of all Criteria.
+func (tr *Tracker) Version() string {
+	h := sha256.New()
+	h.Write([]byte(tr.revision))
 	for _, c := range tr.criteria {
-		tr.trackedCriteria[c.Clone()] = false
+		h.Write([]byte("\n" + c.Describe()))
 	}
-	return tr.trackedCriteria
+	return hex.EncodeToString(h.Sum(nil))[:16]
 }
+
+// trace passes a TraceEvent on to the Tracer if one has been set.
+func (tr *Tracker) trace(c *Criteria, depth int, line int, text string, extracted map[string]any) {
+	if tr.tracer != nil {
+		tr.tracer(&TraceEvent{c, depth, line, text, extracted})
+	}
+}
+
@@ -1,8 +1,8 @@
 package filter
 
 import (
+	"github.com/mmaaskant/gro-crop-scraper/logger"
 	"golang.org/x/net/html"
-	"log"
 	"reflect"
 	"strings"
 )
@@ -24,65 +24,224 @@ func (hf *HtmlFilter) Clone() Filter {
 	filterCopy := *hf
 	trackerCopy := *hf.Tracker
 	filterCopy.Tracker = &trackerCopy
-	filterCopy.criteria = hf.criteria
-	filterCopy.trackedCriteria = make(map[*Criteria]bool)
 	return &filterCopy
 }
 
+// htmlCandidate holds a Criteria that can be matched against upcoming elements, which elements depends on depth.
+// Children are candidates for any element nested deeper than the matched element, until the matched element closes.
+// Siblings are candidates for the first element at the same depth as the matched element, after it has closed.
+type htmlCandidate struct {
+	criteria *Criteria
+	depth    int
+	sibling  bool
+}
+
+// appliesTo checks if the htmlCandidate may be matched against an element at the given depth.
+func (hc *htmlCandidate) appliesTo(depth int) bool {
+	if hc.sibling {
+		return depth == hc.depth
+	}
+	return depth > hc.depth
+}
+
+// htmlMatch holds an element matched by a Criteria which has not been closed yet.
+// Any Extractor that requires more than the matched token itself uses htmlMatch to collect the remaining data,
+// markup is collected for HtmlTableExtractor and the first text within the element for HtmlTextExtractor.
+type htmlMatch struct {
+	criteria    *Criteria
+	depth       int
+	line        int
+	markup      *strings.Builder
+	pendingText bool
+}
+
+func newHtmlMatch(c *Criteria, depth int, line int, t *html.Token) *htmlMatch {
+	var markup *strings.Builder
+	if reflect.TypeOf(c.Extractor) == reflect.TypeOf((*HtmlTableExtractor)(nil)) {
+		markup = new(strings.Builder)
+		markup.WriteString(t.String())
+	}
+	return &htmlMatch{
+		c,
+		depth,
+		line,
+		markup,
+		reflect.TypeOf(c.Extractor) == reflect.TypeOf((*HtmlTextExtractor)(nil)),
+	}
+}
+
+// htmlFilterRun holds the state of a single HtmlFilter.Filter call, the run stops at the first error that occurs.
+type htmlFilterRun struct {
+	filter     *HtmlFilter
+	iterator   *HtmlTokenIterator
+	candidates []*htmlCandidate
+	matches    []*htmlMatch
+	data       map[string]any
+	err        error
+}
+
 // Filter iterates over all tags within the given HTML, and applies Criteria for every found start tag.
 // Any fully matched Criteria that have an Extractor will extract data from the matched tag
-// and return it once the filter is finished.
-func (hf *HtmlFilter) Filter(s string) map[string]any {
-	data := make(map[string]any, 0)
-	ti := newTokenIterator(s)
-	for tt := ti.Next(); tt != html.ErrorToken; tt = ti.Next() {
-		t := ti.Token()
-		for c, _ := range hf.getAllCriteria() {
-			switch tt {
-			case html.SelfClosingTagToken, html.StartTagToken:
-				c.Depth = ti.Depth()
-				if c.Match(&t) {
-					hf.trackedCriteria[c] = true
-					switch {
-					case c.Child != nil:
-						hf.trackedCriteria[c.Child] = false
-					case c.Child == nil:
-						if reflect.TypeOf(c.Extractor) == reflect.TypeOf((*HtmlAttributeExtractor)(nil)) {
-							data = merge(data, c.Extractor.Extract(&t))
-						}
-					}
-				}
-				if reflect.TypeOf(c.Extractor) != reflect.TypeOf((*HtmlTextExtractor)(nil)) {
-					delete(hf.trackedCriteria, c)
-				}
-			case html.TextToken:
-				if len(strings.TrimSpace(t.Data)) != 0 {
-					for c, passed := range hf.trackedCriteria {
-						if passed && c.Child == nil && reflect.TypeOf(c.Extractor) == reflect.TypeOf((*HtmlTextExtractor)(nil)) {
-							if extractedData := c.Extractor.Extract(&t); extractedData != nil {
-								data = merge(data, c.Extractor.Extract(&t))
-							}
-							delete(hf.trackedCriteria, c)
-						}
-					}
-				}
-			case html.EndTagToken:
-				if c.Depth < ti.Depth() {
-					if c.Parent != nil && c.Parent.Child != nil {
-						hf.trackedCriteria[c.Parent] = false
-					}
-					delete(hf.trackedCriteria, c)
-				}
+// and return it once the filter is finished. If any Criteria returns an error the filter stops and returns it.
+func (hf *HtmlFilter) Filter(s string) (map[string]any, error) {
+	run := &htmlFilterRun{
+		hf,
+		newTokenIterator(s),
+		make([]*htmlCandidate, 0),
+		make([]*htmlMatch, 0),
+		make(map[string]any, 0),
+		nil,
+	}
+	for _, c := range hf.criteria {
+		run.addCandidate(c, 0, false)
+	}
+	for tt := run.iterator.Next(); tt != html.ErrorToken && run.err == nil; tt = run.iterator.Next() {
+		t := run.iterator.Token()
+		run.collectMarkup(&t)
+		switch tt {
+		case html.StartTagToken:
+			run.matchElement(run.iterator.Element(tt), run.iterator.Depth(), false)
+		case html.SelfClosingTagToken:
+			run.matchElement(run.iterator.Element(tt), run.iterator.Depth()+1, true)
+		case html.TextToken:
+			if len(strings.TrimSpace(t.Data)) != 0 {
+				run.extractText(&t)
+			}
+		case html.EndTagToken:
+			run.closeElements(run.iterator.Depth())
+		}
+	}
+	if run.err != nil {
+		return nil, run.err
+	}
+	return run.data, nil
+}
+
+// addCandidate adds a Criteria as a candidate, unless it is already a candidate for every element it would apply to.
+func (run *htmlFilterRun) addCandidate(c *Criteria, depth int, sibling bool) {
+	for _, hc := range run.candidates {
+		if hc.criteria == c && hc.sibling == sibling && (hc.depth == depth || (!sibling && hc.depth < depth)) {
+			return
+		}
+	}
+	run.candidates = append(run.candidates, &htmlCandidate{c, depth, sibling})
+}
+
+// matchElement matches all candidates that apply to an element at the given depth,
+// any sibling candidates for this depth are consumed as they only apply to the first element following their match.
+func (run *htmlFilterRun) matchElement(element *HtmlElement, depth int, selfClosing bool) {
+	t := element.Token
+	matched := make([]*Criteria, 0)
+	remaining := run.candidates[:0]
+	for _, hc := range run.candidates {
+		if hc.appliesTo(depth) && run.err == nil {
+			ok, err := hc.criteria.Match(element)
+			if err != nil {
+				run.err = err
+			} else if ok {
+				matched = append(matched, hc.criteria)
+			}
+		}
+		if !hc.sibling || hc.depth != depth {
+			remaining = append(remaining, hc)
+		}
+	}
+	run.candidates = remaining
+	for _, c := range matched {
+		run.filter.trace(c, depth, run.iterator.Line(), t.String(), nil)
+		switch reflect.TypeOf(c.Extractor) {
+		case nil, reflect.TypeOf((*HtmlTextExtractor)(nil)), reflect.TypeOf((*HtmlTableExtractor)(nil)):
+			// Extracted by htmlMatch once the required text or markup has been collected.
+		case reflect.TypeOf((*RegexCaptureExtractor)(nil)):
+			run.extract(c, depth, run.iterator.Line(), element.Text(), element)
+		default:
+			run.extract(c, depth, run.iterator.Line(), t.String(), t)
+		}
+		if selfClosing {
+			for _, sibling := range c.Siblings {
+				run.addCandidate(sibling, depth, true)
 			}
+			continue
+		}
+		for _, child := range c.Children {
+			run.addCandidate(child, depth, false)
+		}
+		run.matches = append(run.matches, newHtmlMatch(c, depth, run.iterator.Line(), t))
+	}
+}
+
+// collectMarkup adds the given token to all matches that collect markup.
+func (run *htmlFilterRun) collectMarkup(t *html.Token) {
+	for _, hm := range run.matches {
+		if hm.markup != nil {
+			hm.markup.WriteString(t.String())
 		}
 	}
-	return data
+}
+
+// extractText extracts the given text token for every match that is waiting for text.
+func (run *htmlFilterRun) extractText(t *html.Token) {
+	for _, hm := range run.matches {
+		if hm.pendingText {
+			hm.pendingText = false
+			run.extract(hm.criteria, run.iterator.Depth(), run.iterator.Line(), t.Data, t)
+		}
+	}
+}
+
+// closeElements handles all matched elements that are closed now the document is back at the given depth,
+// any markup they collected is extracted and their siblings become candidates.
+// Candidates whose scope has been closed are removed.
+func (run *htmlFilterRun) closeElements(depth int) {
+	open := run.matches[:0]
+	closed := make([]*htmlMatch, 0)
+	for _, hm := range run.matches {
+		if hm.depth > depth {
+			closed = append(closed, hm)
+		} else {
+			open = append(open, hm)
+		}
+	}
+	run.matches = open
+	remaining := run.candidates[:0]
+	for _, hc := range run.candidates {
+		if (hc.sibling && hc.depth > depth+1) || (!hc.sibling && hc.depth > depth) {
+			continue
+		}
+		remaining = append(remaining, hc)
+	}
+	run.candidates = remaining
+	for _, hm := range closed {
+		if hm.markup != nil {
+			run.extract(hm.criteria, hm.depth, hm.line, hm.markup.String(), hm.markup.String())
+		}
+		if hm.depth == depth+1 {
+			for _, sibling := range hm.criteria.Siblings {
+				run.addCandidate(sibling, hm.depth, true)
+			}
+		}
+	}
+}
+
+// extract runs the Criteria's Extractor on the given data and merges the results.
+func (run *htmlFilterRun) extract(c *Criteria, depth int, line int, text string, data any) {
+	extractedData, err := c.Extract(data)
+	if err != nil {
+		if run.err == nil {
+			run.err = err
+		}
+		return
+	}
+	if extractedData != nil {
+		run.filter.trace(c, depth, line, text, extractedData)
+		merge(run.data, extractedData)
+	}
 }
 
 func merge(destination map[string]any, source map[string]any) map[string]any { // TODO: Currently does not handle merging of data
 	for k, v := range source {
 		if _, hasKey := destination[k]; hasKey {
-			log.Printf("Filter data aready has key %s with value %s, overwriting ...", k, destination[k])
+			logger.With("key", k).Debugf("Filter data already has key with value %v, overwriting ...", destination[k])
 		}
 		destination[k] = v
 	}
@@ -1,16 +1,69 @@
 package filter
 
 import (
+	"bytes"
 	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
 	"strings"
 )
 
+// voidElements can not have any content and are never closed, so they are treated as self-closing tags.
+var voidElements = map[atom.Atom]bool{
+	atom.Area:   true,
+	atom.Base:   true,
+	atom.Br:     true,
+	atom.Col:    true,
+	atom.Embed:  true,
+	atom.Hr:     true,
+	atom.Img:    true,
+	atom.Input:  true,
+	atom.Link:   true,
+	atom.Meta:   true,
+	atom.Param:  true,
+	atom.Source: true,
+	atom.Track:  true,
+	atom.Wbr:    true,
+}
+
+// optionalEndTags maps elements whose end tag may be omitted to the start tags of the siblings which implicitly close them.
+var optionalEndTags = map[atom.Atom][]atom.Atom{
+	atom.Dd:       {atom.Dd, atom.Dt},
+	atom.Dt:       {atom.Dd, atom.Dt},
+	atom.Li:       {atom.Li},
+	atom.Option:   {atom.Option, atom.Optgroup},
+	atom.Optgroup: {atom.Optgroup},
+	atom.Tbody:    {atom.Tbody, atom.Tfoot},
+	atom.Thead:    {atom.Tbody, atom.Tfoot},
+	atom.Td:       {atom.Td, atom.Th, atom.Tr, atom.Tbody, atom.Tfoot},
+	atom.Th:       {atom.Td, atom.Th, atom.Tr, atom.Tbody, atom.Tfoot},
+	atom.Tr:       {atom.Tr, atom.Tbody, atom.Tfoot},
+	atom.P: {
+		atom.Address, atom.Article, atom.Aside, atom.Blockquote, atom.Div, atom.Dl, atom.Fieldset, atom.Footer,
+		atom.Form, atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Header, atom.Hr, atom.Main, atom.Nav,
+		atom.Ol, atom.P, atom.Pre, atom.Section, atom.Table, atom.Ul,
+	},
+}
+
 // HtmlTokenIterator walks through a given HTML document using html.Tokenizer.
-// The depth of the HTML document is also tracked and is available through Depth().
+// The depth of the HTML document is also tracked and is available through Depth(),
+// as well as the line the current token starts on which is available through Line().
+// Void elements like <br> and <img> are returned as html.SelfClosingTagToken as they are never closed.
+// Tokens read ahead by HtmlElement.Text are buffered, so every token is only tokenized once.
 type HtmlTokenIterator struct {
 	tokenizer *html.Tokenizer
 	token     html.Token
 	tags      []string
+	line      int
+	nextLine  int
+	index     int
+	lookahead []*iteratorToken
+}
+
+// iteratorToken holds a token read by HtmlTokenIterator along with the amount of line breaks it holds.
+type iteratorToken struct {
+	tokenType html.TokenType
+	token     html.Token
+	lines     int
 }
 
 func newTokenIterator(s string) *HtmlTokenIterator {
@@ -19,24 +72,60 @@ func newTokenIterator(s string) *HtmlTokenIterator {
 		tz,
 		tz.Token(),
 		make([]string, 0),
+		1,
+		1,
+		0,
+		make([]*iteratorToken, 0),
 	}
 }
 
 func (ti *HtmlTokenIterator) Next() html.TokenType {
-	tokenType := ti.tokenizer.Next()
-	ti.token = ti.tokenizer.Token()
-	switch tokenType {
+	var it *iteratorToken
+	if len(ti.lookahead) > 0 {
+		it = ti.lookahead[0]
+		ti.lookahead = ti.lookahead[1:]
+	} else {
+		it = ti.read()
+	}
+	ti.index++
+	ti.line = ti.nextLine
+	ti.nextLine += it.lines
+	ti.token = it.token
+	switch it.tokenType {
 	case html.StartTagToken:
 		ti.tags = append(ti.tags, ti.token.Data)
 	case html.EndTagToken:
-		for i := len(ti.tags) - 1; i >= 0; i-- {
-			if ti.tags[i] == ti.token.Data {
-				ti.tags = ti.tags[:i]
-				break
-			}
+		if tags := popTag(ti.tags, ti.token.Data); tags != nil {
+			ti.tags = tags
 		}
 	}
-	return tokenType
+	return it.tokenType
+}
+
+// read reads the next token from the html.Tokenizer.
+func (ti *HtmlTokenIterator) read() *iteratorToken {
+	tokenType := ti.tokenizer.Next()
+	it := &iteratorToken{
+		tokenType,
+		ti.tokenizer.Token(),
+		bytes.Count(ti.tokenizer.Raw(), []byte("\n")),
+	}
+	if tokenType == html.StartTagToken && voidElements[it.token.DataAtom] {
+		it.tokenType = html.SelfClosingTagToken
+	}
+	return it
+}
+
+// peek returns the token at the given index, reading ahead as far as required.
+// Tokens that have already been returned by Next are no longer available, in which case nil is returned.
+func (ti *HtmlTokenIterator) peek(index int) *iteratorToken {
+	if index <= ti.index {
+		return nil
+	}
+	for len(ti.lookahead) < index-ti.index {
+		ti.lookahead = append(ti.lookahead, ti.read())
+	}
+	return ti.lookahead[index-ti.index-1]
 }
 
 func (ti *HtmlTokenIterator) Token() html.Token {
@@ -46,3 +135,114 @@ func (ti *HtmlTokenIterator) Token() html.Token {
 func (ti *HtmlTokenIterator) Depth() int {
 	return len(ti.tags)
 }
+
+func (ti *HtmlTokenIterator) Line() int {
+	return ti.line
+}
+
+// Element returns the current token as an HtmlElement, which gives access to the element's inner text.
+func (ti *HtmlTokenIterator) Element(tt html.TokenType) *HtmlElement {
+	t := ti.token
+	return &HtmlElement{
+		&t,
+		ti,
+		ti.index,
+		tt == html.SelfClosingTagToken,
+		nil,
+	}
+}
+
+// HtmlElement holds the start token of an element and is passed to ConditionInterpreter instances by HtmlFilter.
+// The element's inner text is only looked up once Text is called, as this requires reading ahead in the document.
+type HtmlElement struct {
+	*html.Token
+	iterator    *HtmlTokenIterator
+	index       int
+	selfClosing bool
+	text        *string
+}
+
+// Text returns all text within the element with collapsed whitespace, by reading ahead until the element is closed.
+// The element is also closed by the end tag of one of its parents, or for elements with an optional end tag like <dd>
+// and <li>, by the start tag of a sibling.
+// The text is only available while the element is the iterator's current token, which is when HtmlFilter matches it.
+func (he *HtmlElement) Text() string {
+	if he.text != nil {
+		return *he.text
+	}
+	var sb strings.Builder
+	if !he.selfClosing {
+		tags := make([]string, 0)
+	lookahead:
+		for i := he.index + 1; ; i++ {
+			it := he.iterator.peek(i)
+			if it == nil || it.tokenType == html.ErrorToken {
+				break
+			}
+			switch it.tokenType {
+			case html.TextToken:
+				sb.WriteString(it.token.Data)
+				sb.WriteString(" ")
+			case html.StartTagToken, html.SelfClosingTagToken:
+				if len(tags) == 0 && he.closedBy(it.token.DataAtom) {
+					break lookahead
+				}
+				if it.tokenType == html.StartTagToken {
+					tags = append(tags, it.token.Data)
+				}
+			case html.EndTagToken:
+				if closed := popTag(tags, it.token.Data); closed != nil {
+					tags = closed
+				} else if he.iterator.isOpen(it.token.Data) {
+					break lookahead
+				}
+			}
+		}
+	}
+	text := strings.Join(strings.Fields(sb.String()), " ")
+	he.text = &text
+	return text
+}
+
+// closedBy returns whether the element's end tag may be omitted when it is followed by a sibling with the given tag.
+func (he *HtmlElement) closedBy(a atom.Atom) bool {
+	for _, sibling := range optionalEndTags[he.DataAtom] {
+		if sibling == a {
+			return true
+		}
+	}
+	return false
+}
+
+// isOpen returns whether an element with the given tag is currently open, which includes the current element.
+func (ti *HtmlTokenIterator) isOpen(tag string) bool {
+	for _, t := range ti.tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// popTag removes the last occurrence of the given tag and every tag opened after it from the given tags,
+// nil is returned if the tag is not open.
+func popTag(tags []string, tag string) []string {
+	for i := len(tags) - 1; i >= 0; i-- {
+		if tags[i] == tag {
+			return tags[:i]
+		}
+	}
+	return nil
+}
+
+// htmlToken returns the *html.Token held by the given data, which can either be an *html.Token or an *HtmlElement.
+func htmlToken(data any) (*html.Token, bool) {
+	switch d := data.(type) {
+	case *html.Token:
+		return d, true
+	case *HtmlElement:
+		return d.Token, true
+	default:
+		return nil, false
+	}
+}
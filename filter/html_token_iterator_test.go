@@ -0,0 +1,56 @@
+package filter
+
+import (
+	"golang.org/x/net/html"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHtmlTokenIterator_Line(t *testing.T) {
+	ti := newTokenIterator("<ul>\n<li>One</li>\n\n<li>Two\nlines</li>\n</ul>")
+	lines := make([]int, 0)
+	for tt := ti.Next(); tt != html.ErrorToken; tt = ti.Next() {
+		if tt != html.TextToken || strings.TrimSpace(ti.Token().Data) != "" {
+			lines = append(lines, ti.Line())
+		}
+	}
+	// <ul>, <li>, One, </li>, <li>, Two lines, </li>, </ul>
+	expected := []int{1, 2, 2, 2, 4, 4, 5, 6}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("Got lines %v, expected: %v", lines, expected)
+	}
+}
+
+func TestHtmlElement_Text(t *testing.T) {
+	ti := newTokenIterator("<div>\n<p>Full <b>Sun</b></p>\n<div>Nested</div>\n</div>\n<p>After</p>")
+	texts := make([]string, 0)
+	lines := make([]int, 0)
+	for tt := ti.Next(); tt != html.ErrorToken; tt = ti.Next() {
+		if tt == html.StartTagToken {
+			texts = append(texts, ti.Element(tt).Text())
+			lines = append(lines, ti.Line())
+		}
+	}
+	// <div>, <p>, <b>, nested <div>, <p>, every token read ahead by Text is still returned by Next.
+	expectedTexts := []string{"Full Sun Nested", "Full Sun", "Sun", "Nested", "After"}
+	expectedLines := []int{1, 2, 2, 3, 5}
+	if !reflect.DeepEqual(texts, expectedTexts) || !reflect.DeepEqual(lines, expectedLines) {
+		t.Errorf("Got texts %q on lines %v, expected: %q on lines %v", texts, lines, expectedTexts, expectedLines)
+	}
+}
+
+func TestHtmlElement_TextOptionalEndTags(t *testing.T) {
+	ti := newTokenIterator("<dl><dt>Sun<dd>Full<dt>Height<dd>12in</dl><p>After")
+	texts := make([]string, 0)
+	for tt := ti.Next(); tt != html.ErrorToken; tt = ti.Next() {
+		if tt == html.StartTagToken {
+			texts = append(texts, ti.Element(tt).Text())
+		}
+	}
+	// <dl>, <dt>, <dd>, <dt>, <dd>, <p>, every <dt> and <dd> is closed by its next sibling or by </dl>.
+	expected := []string{"Sun Full Height 12in", "Sun", "Full", "Height", "12in", "After"}
+	if !reflect.DeepEqual(texts, expected) {
+		t.Errorf("Got texts %q, expected: %q", texts, expected)
+	}
+}
@@ -2,7 +2,8 @@ package filter
 
 import (
 	"encoding/json"
-	"log"
+	"fmt"
+	"sort"
 )
 
 // JsonFilter implements Filter and iterates over the given JSON,
@@ -22,50 +23,94 @@ func (jf *JsonFilter) Clone() Filter {
 	filterCopy := *jf
 	trackerCopy := *jf.Tracker
 	filterCopy.Tracker = &trackerCopy
-	filterCopy.criteria = jf.criteria
-	filterCopy.trackedCriteria = make(map[*Criteria]bool)
 	return &filterCopy
 }
 
-func (jf *JsonFilter) Filter(s string) map[string]any {
+// Filter unmarshals the given JSON and walks it, any extracted data is returned once the filter is finished.
+// If the JSON is invalid or any Criteria returns an error the filter stops and returns it.
+func (jf *JsonFilter) Filter(s string) (map[string]any, error) {
 	data := make(map[string]any, 0)
 	var js map[string]any
 	err := json.Unmarshal([]byte(s), &js)
 	if err != nil {
-		log.Printf("Failed to unmarshal JSON %s, error: %s", s, err)
-		return nil
+		return nil, fmt.Errorf("failed to unmarshal JSON, error: %w", err)
 	}
-	jf.Walk(js, data)
-	return data
+	if err = jf.Walk(js, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Walk matches every key value pair within the given JSON against the root Criteria, including those of nested objects.
+// Any extracted data is added to data, walking stops at the first error returned by a Criteria.
+func (jf *JsonFilter) Walk(js map[string]any, data map[string]any) error {
+	return jf.walk(js, data, jf.criteria, 1)
+}
+
+// walk matches every key value pair within js against the given Criteria, nested objects are walked using the same Criteria.
+func (jf *JsonFilter) walk(js map[string]any, data map[string]any, criteria []*Criteria, depth int) error {
+	for _, k := range sortedKeys(js) {
+		if walkable, ok := js[k].(map[string]any); ok {
+			if err := jf.walk(walkable, data, criteria, depth+1); err != nil {
+				return err
+			}
+		}
+		for _, c := range criteria {
+			if err := jf.match(c, js, k, data, depth); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
 }
 
-func (jf *JsonFilter) Walk(js map[string]any, data map[string]any, depths ...int) {
-	var depth int
-	if len(depths) == 0 {
-		depths = append(depths, 1)
+// match matches the Criteria against the key value pair of js found at key k and extracts it if the Criteria has an Extractor.
+// If it matched, its children are matched against the pair's value if it is an object,
+// and its siblings are matched against the other key value pairs within js.
+func (jf *JsonFilter) match(c *Criteria, js map[string]any, k string, data map[string]any, depth int) error {
+	pair := map[string]any{k: js[k]}
+	ok, err := c.Match(pair)
+	if err != nil || !ok {
+		return err
 	}
-	depth = depths[0]
-	children := make([]*Criteria, 0)
-	for k, v := range js {
-		if walkable, ok := v.(map[string]any); ok {
-			jf.Walk(walkable, data, depth+1)
+	text := fmt.Sprintf("%s: %v", k, js[k])
+	jf.trace(c, depth, 0, text, nil)
+	extractedData, err := c.Extract(pair)
+	if err != nil {
+		return err
+	}
+	if extractedData != nil { // TODO: Doesn't support merge
+		jf.trace(c, depth, 0, text, extractedData)
+		for ek, ev := range extractedData {
+			if ev != nil {
+				data[ek] = ev
+			}
+		}
+	}
+	if walkable, ok := js[k].(map[string]any); ok && len(c.Children) > 0 {
+		if err = jf.walk(walkable, data, c.Children, depth+1); err != nil {
+			return err
 		}
-		for c, _ := range jf.getAllCriteria() {
-			matched := c.Match(map[string]any{k: v})
-			switch {
-			case !matched && c.Parent == nil:
-				delete(jf.trackedCriteria, c)
-			case matched && c.Child != nil:
-				children = append(children, c.Child)
-				jf.trackedCriteria[c.Child] = false
-			case matched && c.Child == nil && c.Extractor != nil:
-				if extractedData := c.Extractor.Extract(map[string]any{k: v})[k]; extractedData != nil { // TODO: Doesn't support merge
-					data[k] = extractedData
-				}
+	}
+	for _, sibling := range c.Siblings {
+		for _, siblingKey := range sortedKeys(js) {
+			if siblingKey == k {
+				continue
+			}
+			if err = jf.match(sibling, js, siblingKey, data, depth); err != nil {
+				return err
 			}
 		}
 	}
-	for _, child := range children {
-		delete(jf.trackedCriteria, child)
+	return nil
+}
+
+// sortedKeys returns the keys of the given JSON object in alphabetical order, so it is always walked in the same order.
+func sortedKeys(js map[string]any) []string {
+	keys := make([]string, 0, len(js))
+	for k := range js {
+		keys = append(keys, k)
 	}
+	sort.Strings(keys)
+	return keys
 }
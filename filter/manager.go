@@ -1,25 +1,42 @@
 package filter
 
 import (
+	"errors"
 	"fmt"
 	"github.com/mmaaskant/gophervisor/supervisor"
 	"github.com/mmaaskant/gro-crop-scraper/database"
 	"github.com/mmaaskant/gro-crop-scraper/helper"
+	"github.com/mmaaskant/gro-crop-scraper/logger"
+	"github.com/mmaaskant/gro-crop-scraper/metrics"
 	"go.mongodb.org/mongo-driver/mongo"
-	"log"
 	"reflect"
+	"runtime/debug"
+	"sync"
+	"time"
 )
 
+// StepId identifies the filter step, e.g. within the counters of a run, logs and the "dead_letters" table.
+const StepId = "filter"
+
 // Manager oversees all Filter instances manages workers to run them in using supervisor.Supervisor.
+// Every Filter is run by its own workers, so a Filter with many documents to filter does not starve the others.
 type Manager struct {
-	db      *database.Db
-	filters []Filter
+	db         *database.Db
+	filters    []Filter
+	workers    map[Filter]int
+	provenance bool
+	refilter   bool
+	retention  time.Duration
 }
 
 func NewManager(db *database.Db) *Manager {
 	return &Manager{
 		db,
 		make([]Filter, 0),
+		make(map[Filter]int),
+		false,
+		false,
+		0,
 	}
 }
 
@@ -36,6 +53,19 @@ func newFilterJob(f Filter, e *database.Entity) *filterJob {
 	}
 }
 
+// logger returns a logger.Logger holding the current run, the config and scraper ID of the filterJob's Filter
+// and the document it filters.
+func (m *Manager) logger(fj *filterJob) *logger.Logger {
+	return logger.With(
+		"run_id", m.db.CurrentRun().Id(),
+		"config_id", fj.filter.GetConfigId(),
+		"scraper_id", fj.filter.GetScraperId(),
+		"step", StepId,
+		"url", fj.entity.Data["url"],
+		"document_id", fj.entity.Id,
+	)
+}
+
 func (m *Manager) RegisterFilters(filters []Filter) {
 	for _, f := range filters {
 		m.RegisterFilter(f)
@@ -46,51 +76,236 @@ func (m *Manager) RegisterFilter(f Filter) {
 	m.filters = append(m.filters, f)
 }
 
-// Start starts an amount of Filter workers based on the amountOfWorkers parameter.
-// All data in the "scraped_data" table will be queued to be filtered.
-func (m *Manager) Start(amountOfWorkers int) {
-	sv, p, _ := helper.StartSupervisor(amountOfWorkers, m.filter)
+// SetWorkerCount sets the amount of workers the given Filter is run by,
+// a count of 0 uses the default amount of workers passed to Start.
+func (m *Manager) SetWorkerCount(f Filter, amountOfWorkers int) {
+	m.workers[f] = amountOfWorkers
+}
+
+// SetProvenance determines if the Provenance of all filtered data is stored alongside it in the "filtered_data" table.
+func (m *Manager) SetProvenance(enabled bool) {
+	m.provenance = enabled
+}
+
+// SetRefilter determines if data in the "scraped_data" table that has been filtered by a different version of its Filter
+// is filtered again, rather than only filtering data that has not been filtered yet.
+func (m *Manager) SetRefilter(enabled bool) {
+	m.refilter = enabled
+}
+
+// SetRetention determines how long filtered data is kept in the "scraped_data" table after it has been crawled,
+// a retention of 0 keeps it indefinitely.
+func (m *Manager) SetRetention(retention time.Duration) {
+	m.retention = retention
+}
+
+// Start runs every Filter concurrently using its own supervisor.Supervisor instance, which starts the amount of workers
+// set by SetWorkerCount or defaultAmountOfWorkers if none was set.
+// All data in the "scraped_data" table that has not been filtered yet will be queued to be filtered,
+// or all data that has not been filtered by the Filter's current version if refilter is enabled.
+// Once all Filter instances have finished, any filtered data older than the retention period is removed.
+func (m *Manager) Start(defaultAmountOfWorkers int) {
+	wg := sync.WaitGroup{}
+	panics := make(chan any, len(m.filters))
 	for _, f := range m.filters {
-		iterator, err := m.db.GetMany(database.ScrapedDataTableName, map[string]any{"scraper_id": f.GetScraperId()})
-		if err != nil {
-			log.Panicf("Failed to initialise iterator, error: %s", err)
-		}
-		for e, _ := iterator.Next(); e != nil; e, _ = iterator.Next() {
-			p.Publish(newFilterJob(f, e))
+		amountOfWorkers := defaultAmountOfWorkers
+		if m.workers[f] > 0 {
+			amountOfWorkers = m.workers[f]
 		}
+		wg.Add(1)
+		go func(f Filter) {
+			defer wg.Done()
+			defer func() {
+				if r := recover(); r != nil {
+					panics <- r
+				}
+			}()
+			m.run(amountOfWorkers, f)
+		}(f)
+	}
+	wg.Wait()
+	close(panics)
+	// Panics are passed on once all Filter instances have finished, so they can be recovered by the caller.
+	if r, ok := <-panics; ok {
+		panic(r)
+	}
+	m.purge()
+}
+
+// run queues all data in the "scraped_data" table the Filter should filter for the given amount of workers,
+// and waits until all of it has been filtered.
+func (m *Manager) run(amountOfWorkers int, f Filter) {
+	sv, p, _ := helper.StartSupervisor(amountOfWorkers, m.filter)
+	defer sv.Shutdown()
+	params := map[string]any{"scraper_id": f.GetScraperId(), "filter_version": nil}
+	if m.refilter {
+		params["filter_version"] = map[string]any{"$ne": f.Version()}
+	}
+	iterator, err := m.db.GetMany(database.ScrapedDataTableName, params)
+	if err != nil {
+		logger.With("run_id", m.db.CurrentRun().Id(), "config_id", f.GetConfigId(), "scraper_id", f.GetScraperId(), "step", StepId).Panicf("Failed to initialise iterator, error: %s", err)
+	}
+	for e, _ := iterator.Next(); e != nil; e, _ = iterator.Next() {
+		m.publish(p, newFilterJob(f, e))
+	}
+}
+
+// purge removes all filtered data from the "scraped_data" table that was crawled before the retention period,
+// along with any raw bodies that are no longer referenced.
+func (m *Manager) purge() {
+	if m.retention <= 0 {
+		return
+	}
+	err := m.db.DeleteMany(database.ScrapedDataTableName, map[string]any{
+		"filter_version": map[string]any{"$ne": nil},
+		"crawled_at":     map[string]any{"$lt": time.Now().Add(-m.retention)},
+	})
+	if err != nil {
+		logger.With("run_id", m.db.CurrentRun().Id(), "step", StepId, "error", err).Errorf("Failed to purge scraped data older than %s", m.retention)
+		return
+	}
+	if err = m.db.PurgeRawBodies(); err != nil {
+		logger.With("run_id", m.db.CurrentRun().Id(), "step", StepId, "error", err).Errorf("Failed to purge unreferenced raw bodies")
+	}
+}
+
+// Reprocess filters the given "scraped_data" database.Entity instances using the given Filter,
+// e.g. those of documents that failed to be filtered before.
+func (m *Manager) Reprocess(amountOfWorkers int, f Filter, entities []*database.Entity) {
+	sv, p, _ := helper.StartSupervisor(amountOfWorkers, m.filter)
+	for _, e := range entities {
+		m.publish(p, newFilterJob(f, e))
 	}
 	sv.Shutdown()
 }
 
+// publish queues the filterJob for Manager's workers and tracks the queue's depth.
+func (m *Manager) publish(p *supervisor.Publisher, fj *filterJob) {
+	metrics.QueueDepth.WithLabelValues(StepId).Inc()
+	p.Publish(fj)
+}
+
 // filter receives filterJob instances and processes these, any extracted data is saved in the "filtered_data" table.
+// Any error or panic that occurs while filtering is recorded in the "errors" table so the remaining jobs can continue.
 func (m *Manager) filter(p *supervisor.Publisher, d any, rch chan any) {
 	var fj *filterJob
 	fj, ok := d.(*filterJob)
 	if !ok {
-		log.Panicf("Expected instance of %s, got %s", reflect.TypeOf(fj), reflect.TypeOf(d))
-	}
-	if data := fj.filter.Filter(fmt.Sprint(fj.entity.Data["data"])); data != nil {
-		if fe, err := m.db.GetOne(database.FilteredDataTableName, map[string]any{"url": fj.entity.Data["url"]}); err != mongo.ErrNoDocuments {
-			fe.Data["data"] = data
-			if err = m.db.UpdateOne(fe); err != nil {
-				log.Panicf("Failed to update filtered data, error: %s", err)
-			}
+		logger.Panicf("Expected instance of %s, got %s", reflect.TypeOf(fj), reflect.TypeOf(d))
+	}
+	defer metrics.QueueDepth.WithLabelValues(StepId).Dec()
+	defer func() {
+		if r := recover(); r != nil {
+			m.recordError(fj, fmt.Errorf("filter panicked: %v", r), debug.Stack())
+		}
+	}()
+	if err := m.filterEntity(fj); err != nil {
+		var st stackTracer
+		if errors.As(err, &st) {
+			m.recordError(fj, err, st.Stack())
+		} else {
+			m.recordError(fj, err, debug.Stack())
+		}
+	}
+}
+
+// stackTracer is implemented by errors that hold the stack at the time they occurred, like TypeError.
+type stackTracer interface {
+	Stack() []byte
+}
+
+// filterEntity filters the data held by the filterJob's database.Entity and saves the results,
+// both the results and the database.Entity are stamped with the version of the Filter that was used.
+func (m *Manager) filterEntity(fj *filterJob) error {
+	var data map[string]any
+	var provenance map[string]*Provenance
+	var err error
+	if m.provenance {
+		data, provenance, err = FilterWithProvenance(fj.filter, fmt.Sprint(fj.entity.Data["data"]))
+	} else {
+		data, err = fj.filter.Filter(fmt.Sprint(fj.entity.Data["data"]))
+	}
+	if err != nil {
+		return err
+	}
+	version := fj.filter.Version()
+	if data != nil {
+		if err = m.store(fj, data, provenance, version); err != nil {
+			return err
+		}
+		m.db.CurrentRun().Count(fj.filter.GetScraperId(), StepId, "extracted")
+		metrics.FilterResults.WithLabelValues(fj.filter.GetScraperId(), "hit").Inc()
+	} else {
+		metrics.FilterResults.WithLabelValues(fj.filter.GetScraperId(), "miss").Inc()
+	}
+	fj.entity.Data["filter_version"] = version
+	fj.entity.Data["filter_run_id"] = m.db.CurrentRun().Id()
+	if err = m.db.UpdateOne(fj.entity); err != nil {
+		return fmt.Errorf("failed to update filter version of scraped data, error: %w", err)
+	}
+	m.db.CurrentRun().Count(fj.filter.GetScraperId(), StepId, "filtered")
+	return nil
+}
+
+// store saves the filtered data in the "filtered_data" table, replacing any data filtered earlier for the same URL.
+func (m *Manager) store(fj *filterJob, data map[string]any, provenance map[string]*Provenance, version string) error {
+	if fe, err := m.db.GetOne(database.FilteredDataTableName, map[string]any{"url": fj.entity.Data["url"]}); err != mongo.ErrNoDocuments {
+		if err != nil {
+			return fmt.Errorf("failed to fetch filtered data, error: %w", err)
+		}
+		fe.Data["data"] = data
+		fe.Data["filter_version"] = version
+		if provenance != nil {
+			fe.Data["provenance"] = provenance
 		} else {
-			e := database.NewEntity(
-				database.FilteredDataTableName,
-				map[string]any{
-					"url":        fj.entity.Data["url"],
-					"config_id":  fj.filter.GetConfigId(),
-					"scraper_id": fj.filter.GetScraperId(),
-					"data":       data,
-				},
-			)
-			if err = m.db.InsertOne(e); err != nil {
-				log.Panicf("Failed to insert filtered data, error: %s", err)
-			}
-			if err = m.db.DeleteOne(fj.entity); err != nil {
-				log.Panicf("Failed to delete scraped data, error: %s", err)
-			}
+			delete(fe.Data, "provenance")
 		}
+		m.db.StampRun(fe)
+		if err = m.db.UpdateOne(fe); err != nil {
+			return fmt.Errorf("failed to update filtered data, error: %w", err)
+		}
+		return nil
+	}
+	e := database.NewEntity(
+		database.FilteredDataTableName,
+		map[string]any{
+			"url":            fj.entity.Data["url"],
+			"config_id":      fj.filter.GetConfigId(),
+			"scraper_id":     fj.filter.GetScraperId(),
+			"data":           data,
+			"filter_version": version,
+		},
+	)
+	if provenance != nil {
+		e.Data["provenance"] = provenance
+	}
+	if err := m.db.InsertOne(e); err != nil {
+		return fmt.Errorf("failed to insert filtered data, error: %w", err)
+	}
+	return nil
+}
+
+// recordError logs the error and stores it in the "errors" table along with the document it occurred for,
+// the document is added to the "dead_letters" table so it can be requeued.
+func (m *Manager) recordError(fj *filterJob, err error, stack []byte) {
+	m.db.CurrentRun().Count(fj.filter.GetScraperId(), StepId, "failed")
+	metrics.FilterResults.WithLabelValues(fj.filter.GetScraperId(), "error").Inc()
+	l := m.logger(fj)
+	l.With("error", err).Errorf("Failed to filter document, skipping ...")
+	recordErr := m.db.RecordError(map[string]any{
+		"step":        StepId,
+		"document_id": fj.entity.Id,
+		"config_id":   fj.filter.GetConfigId(),
+		"scraper_id":  fj.filter.GetScraperId(),
+		"url":         fj.entity.Data["url"],
+	}, err, stack)
+	if recordErr != nil {
+		l.With("error", recordErr).Errorf("Failed to record filter error")
+	}
+	recordErr = m.db.AddDeadLetter(StepId, fj.filter.GetConfigId(), fj.filter.GetScraperId(), fmt.Sprint(fj.entity.Data["url"]), map[string]any{
+		"document_id": fj.entity.Id,
+	}, err)
+	if recordErr != nil {
+		l.With("error", recordErr).Errorf("Failed to add filter dead letter")
 	}
 }
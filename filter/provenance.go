@@ -0,0 +1,75 @@
+package filter
+
+import (
+	"unicode/utf8"
+)
+
+// maxProvenanceTextLength caps the raw text stored per Provenance, as a single match can hold an entire script.
+const maxProvenanceTextLength = 1024
+
+// Provenance describes which Criteria extracted a key, where in the source document it was found and the raw text
+// it was extracted from. Line is only available for HTML documents and is 0 otherwise.
+type Provenance struct {
+	Criteria string `bson:"criteria" json:"criteria"`
+	Depth    int    `bson:"depth" json:"depth"`
+	Line     int    `bson:"line" json:"line"`
+	Text     string `bson:"text" json:"text"`
+}
+
+// ProvenanceRecorder records the Provenance of every key extracted by a Filter,
+// it is registered as the Filter's Tracer using ProvenanceRecorder.Trace.
+type ProvenanceRecorder struct {
+	provenance map[string]*Provenance
+}
+
+func NewProvenanceRecorder() *ProvenanceRecorder {
+	return &ProvenanceRecorder{
+		make(map[string]*Provenance),
+	}
+}
+
+// Trace implements Tracer and records the Provenance of all keys within the TraceEvent's extracted data,
+// as Filter overwrites keys that are extracted more than once the last Provenance is kept.
+func (pr *ProvenanceRecorder) Trace(e *TraceEvent) {
+	if e.Extracted == nil {
+		return
+	}
+	text := TruncateText(e.Text, maxProvenanceTextLength)
+	for k := range e.Extracted {
+		pr.provenance[k] = &Provenance{e.Criteria.Path(), e.Depth, e.Line, text}
+	}
+}
+
+// TruncateText truncates the given text to at most maxLength bytes without splitting a multibyte character.
+func TruncateText(text string, maxLength int) string {
+	if len(text) <= maxLength {
+		return text
+	}
+	for maxLength > 0 && !utf8.RuneStart(text[maxLength]) {
+		maxLength--
+	}
+	return text[:maxLength]
+}
+
+// Provenance returns the recorded Provenance mapped by the key it describes.
+func (pr *ProvenanceRecorder) Provenance() map[string]*Provenance {
+	return pr.provenance
+}
+
+// FilterWithProvenance runs a clone of the given Filter and returns the filtered data along with its Provenance,
+// if the Filter does not implement Traceable no Provenance is returned.
+func FilterWithProvenance(f Filter, s string) (map[string]any, map[string]*Provenance, error) {
+	f = f.Clone()
+	tf, ok := f.(Traceable)
+	if !ok {
+		data, err := f.Filter(s)
+		return data, nil, err
+	}
+	pr := NewProvenanceRecorder()
+	tf.SetTracer(pr.Trace)
+	data, err := f.Filter(s)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, pr.Provenance(), nil
+}
@@ -0,0 +1,48 @@
+package filter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFilterWithProvenance(t *testing.T) {
+	c := NewCriteria(NewHtmlTableExtractor(nil), NewHtmlTokenTagInterpreter("dl"), NewHtmlTokenAttributeInterpreter("class", "details"))
+	data, provenance, err := FilterWithProvenance(NewHtmlFilter(c), testSpecificationHtml)
+	if err != nil {
+		t.Fatalf("Failed to filter, error: %s", err)
+	}
+	if data["mature_height"] != `24"` {
+		t.Errorf("Got mature_height %v, expected: %s", data["mature_height"], `24"`)
+	}
+	p, ok := provenance["mature_height"]
+	if !ok {
+		t.Fatalf("Expected provenance for mature_height, got: %v", provenance)
+	}
+	expected := Provenance{
+		"tag[dl] attribute[class=details]",
+		4,
+		8,
+		"<dl class=\"details\">\n\t\t<dt>Mature Height</dt><dd>24&#34;</dd>\n\t\t<dt>Growing Zones</dt><dd>3</dd><dd>4</dd>\n\t</dl>",
+	}
+	if *p != expected {
+		t.Errorf("Got provenance %v, expected: %v", *p, expected)
+	}
+	if len(provenance) != len(data) {
+		t.Errorf("Got provenance for %d keys, expected: %d", len(provenance), len(data))
+	}
+}
+
+func TestProvenanceRecorder_TruncatesText(t *testing.T) {
+	// The limit falls within the two bytes of the first "é", which should not be split.
+	text := strings.Repeat("a", maxProvenanceTextLength-1) + strings.Repeat("é", 10)
+	pr := NewProvenanceRecorder()
+	pr.Trace(&TraceEvent{NewCriteria(nil, NewHtmlTokenTagInterpreter("p")), 1, 1, text, map[string]any{"notes": text}})
+	p := pr.Provenance()["notes"]
+	if p == nil || p.Text != strings.Repeat("a", maxProvenanceTextLength-1) {
+		t.Errorf("Expected the text to be truncated before the first multibyte character, got %v", p)
+	}
+	pr.Trace(&TraceEvent{NewCriteria(nil, NewHtmlTokenTagInterpreter("p")), 1, 1, "é", map[string]any{"notes": "é"}})
+	if p = pr.Provenance()["notes"]; p.Text != "é" {
+		t.Errorf("Expected text within the limit to be kept, got %q", p.Text)
+	}
+}
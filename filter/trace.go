@@ -0,0 +1,22 @@
+package filter
+
+// TraceEvent describes a Criteria that matched while a Filter was running, and the data it extracted if any.
+// Line and Depth refer to the location of the matched data within the filtered document,
+// Line is only available for HTML documents and is 0 otherwise.
+type TraceEvent struct {
+	Criteria  *Criteria
+	Depth     int
+	Line      int
+	Text      string
+	Extracted map[string]any
+}
+
+// Tracer receives a TraceEvent every time a Criteria matches or extracts data.
+// Filters run by a Clean function are not traced, any data they extract is part of the TraceEvent of the Criteria
+// whose Extractor ran the Clean function.
+type Tracer func(e *TraceEvent)
+
+// Traceable allows a Filter to report its progress to a Tracer.
+type Traceable interface {
+	SetTracer(t Tracer)
+}
@@ -0,0 +1,65 @@
+package filter
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHtmlFilter_Trace(t *testing.T) {
+	product := NewCriteria(nil, NewHtmlTokenTagInterpreter("div"), NewHtmlTokenAttributeInterpreter("class", "^product$"))
+	product.AddChildren(
+		NewCriteria(NewHtmlTextExtractor("name"), NewHtmlTokenTagInterpreter("h1")),
+		NewCriteria(nil, NewHtmlTokenTagInterpreter("div")).AddChildren(
+			NewCriteria(nil, NewHtmlTokenTagInterpreter("h4")).AddSiblings(
+				NewCriteria(NewHtmlTextExtractor("notes"), NewHtmlTokenTagInterpreter("p")),
+			),
+		),
+	)
+	type event struct {
+		path      string
+		depth     int
+		line      int
+		text      string
+		extracted map[string]any
+	}
+	events := make([]event, 0)
+	f := NewHtmlFilter(product)
+	f.SetTracer(func(e *TraceEvent) {
+		events = append(events, event{e.Criteria.Path(), e.Depth, e.Line, e.Text, e.Extracted})
+	})
+	if _, err := f.Filter(testProductHtml); err != nil {
+		t.Fatalf("Failed to filter, error: %s", err)
+	}
+	root := "tag[div] attribute[class=^product$]"
+	expected := []event{
+		{root, 3, 2, `<div class="product">`, nil},
+		{root + " > tag[h1]", 4, 3, `<h1 class="title">`, nil},
+		{root + " > tag[h1]", 4, 3, "Tomato, Sungold Hybrid", map[string]any{"name": "Tomato, Sungold Hybrid"}},
+		{root + " > tag[div]", 4, 5, `<div class="details">`, nil},
+		{root + " > tag[div] > tag[h4]", 5, 10, "<h4>", nil},
+		{root + " > tag[div] > tag[h4] + tag[p]", 5, 11, "<p>", nil},
+		{root + " > tag[div] > tag[h4] + tag[p]", 5, 11, "Indeterminate", map[string]any{"notes": "Indeterminate"}},
+	}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("Got trace events %v, expected: %v", events, expected)
+	}
+}
+
+func TestCriteria_String(t *testing.T) {
+	keyExpr, valueExpr := "^name$", "Tomato"
+	for _, tc := range []struct {
+		description string
+		expected    string
+	}{
+		{NewCondition(&keyExpr, &valueExpr).String(), "^name$=Tomato"},
+		{NewCondition(&keyExpr, nil).String(), "^name$"},
+		{NewCondition(nil, &valueExpr).String(), "=Tomato"},
+		{NewCondition(nil, nil).String(), ""},
+		{NewCriteria(nil).String(), ""},
+		{NewCriteria(nil, NewHtmlTokenTagInterpreter("h3"), NewHtmlTokenAttributeInterpreter("class", "title")).String(), "tag[h3] attribute[class=title]"},
+	} {
+		if tc.description != tc.expected {
+			t.Errorf("Got %q, expected: %q", tc.description, tc.expected)
+		}
+	}
+}
@@ -1,7 +1,7 @@
 package helper
 
 import (
-	"log"
+	"github.com/mmaaskant/gro-crop-scraper/logger"
 	"regexp"
 )
 
@@ -12,7 +12,17 @@ func CompileRegex(expr *string) *regexp.Regexp {
 	}
 	regex, err := regexp.Compile(*expr)
 	if err != nil {
-		log.Panicf("Failed to compile regex %v, error: %s", expr, err)
+		logger.Panicf("Failed to compile regex %v, error: %s", expr, err)
 	}
 	return regex
 }
+
+// HasNamedGroup checks if the given regex holds at least one named capture group, e.g. (?P<name>...).
+func HasNamedGroup(regex *regexp.Regexp) bool {
+	for _, name := range regex.SubexpNames() {
+		if name != "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,284 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level determines which log lines are written, lines below the configured Level are discarded.
+type Level int
+
+const (
+	DebugLevel Level = iota
+	InfoLevel
+	WarnLevel
+	ErrorLevel
+)
+
+var levelNames = map[Level]string{
+	DebugLevel: "debug",
+	InfoLevel:  "info",
+	WarnLevel:  "warn",
+	ErrorLevel: "error",
+}
+
+func (l Level) String() string {
+	return levelNames[l]
+}
+
+// ParseLevel converts the name of a Level, e.g. "debug", to a Level.
+func ParseLevel(name string) (Level, error) {
+	for l, n := range levelNames {
+		if strings.EqualFold(n, name) {
+			return l, nil
+		}
+	}
+	return InfoLevel, fmt.Errorf("unknown log level %s, expected debug, info, warn or error", name)
+}
+
+const (
+	LogfmtFormat = "logfmt"
+	JsonFormat   = "json"
+)
+
+// output holds the settings shared by all Logger instances.
+type output struct {
+	mu     sync.Mutex
+	w      io.Writer
+	level  Level
+	format string
+}
+
+var out = &output{
+	w:      os.Stderr,
+	level:  InfoLevel,
+	format: LogfmtFormat,
+}
+
+var defaultLogger = &Logger{}
+var defaultMu sync.RWMutex
+
+// init configures logging using the LOG_LEVEL and LOG_FORMAT env variables, invalid values are reported and ignored.
+func init() {
+	if err := Configure(os.Getenv("LOG_LEVEL"), os.Getenv("LOG_FORMAT")); err != nil {
+		Warnf("Ignoring invalid logging env variables, error: %s", err)
+	}
+}
+
+// Configure sets the Level and format by name, empty names keep the current setting.
+func Configure(levelName string, format string) error {
+	if levelName != "" {
+		l, err := ParseLevel(levelName)
+		if err != nil {
+			return err
+		}
+		SetLevel(l)
+	}
+	if format != "" {
+		return SetFormat(format)
+	}
+	return nil
+}
+
+// SetLevel sets the minimum Level of the lines that are written.
+func SetLevel(l Level) {
+	out.mu.Lock()
+	defer out.mu.Unlock()
+	out.level = l
+}
+
+// SetFormat sets the format lines are written in, either LogfmtFormat or JsonFormat.
+func SetFormat(format string) error {
+	if format != LogfmtFormat && format != JsonFormat {
+		return fmt.Errorf("unknown log format %s, expected %s or %s", format, LogfmtFormat, JsonFormat)
+	}
+	out.mu.Lock()
+	defer out.mu.Unlock()
+	out.format = format
+	return nil
+}
+
+// SetOutput sets the io.Writer lines are written to, os.Stderr is used by default.
+func SetOutput(w io.Writer) {
+	out.mu.Lock()
+	defer out.mu.Unlock()
+	out.w = w
+}
+
+type field struct {
+	key   string
+	value any
+}
+
+// Logger writes structured log lines which hold its fields, e.g. the config and scraper ID of the component logging.
+// Logger is immutable, With returns a new Logger so it is safe to share between goroutines.
+type Logger struct {
+	fields []field
+}
+
+// Default returns the Logger used by the package level functions, its fields are added to every line they write.
+func Default() *Logger {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultLogger
+}
+
+// SetDefault replaces the Logger returned by Default, e.g. to add the ID of the current run to every line.
+func SetDefault(l *Logger) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultLogger = l
+}
+
+// With returns a Logger based on Default holding the given key-value pairs.
+func With(keysAndValues ...any) *Logger {
+	return Default().With(keysAndValues...)
+}
+
+// With returns a copy of the Logger that also holds the given key-value pairs, keys must be strings.
+func (l *Logger) With(keysAndValues ...any) *Logger {
+	fields := make([]field, len(l.fields), len(l.fields)+len(keysAndValues)/2)
+	copy(fields, l.fields)
+	for i := 0; i < len(keysAndValues); i += 2 {
+		key := fmt.Sprint(keysAndValues[i])
+		if i+1 >= len(keysAndValues) {
+			fields = append(fields, field{"!BADKEY", key})
+			break
+		}
+		fields = append(fields, field{key, keysAndValues[i+1]})
+	}
+	return &Logger{fields}
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx holding the given Logger, e.g. so an http.Request carries the fields of its caller.
+func NewContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the Logger held by ctx, or false if it does not hold one.
+func FromContext(ctx context.Context) (*Logger, bool) {
+	l, ok := ctx.Value(contextKey{}).(*Logger)
+	return l, ok
+}
+
+func (l *Logger) Debugf(format string, args ...any) {
+	l.log(DebugLevel, format, args...)
+}
+
+func (l *Logger) Infof(format string, args ...any) {
+	l.log(InfoLevel, format, args...)
+}
+
+func (l *Logger) Warnf(format string, args ...any) {
+	l.log(WarnLevel, format, args...)
+}
+
+func (l *Logger) Errorf(format string, args ...any) {
+	l.log(ErrorLevel, format, args...)
+}
+
+// Panicf writes an error line and panics with its message.
+func (l *Logger) Panicf(format string, args ...any) {
+	l.log(ErrorLevel, format, args...)
+	panic(fmt.Sprintf(format, args...))
+}
+
+func Debugf(format string, args ...any) {
+	Default().log(DebugLevel, format, args...)
+}
+
+func Infof(format string, args ...any) {
+	Default().log(InfoLevel, format, args...)
+}
+
+func Warnf(format string, args ...any) {
+	Default().log(WarnLevel, format, args...)
+}
+
+func Errorf(format string, args ...any) {
+	Default().log(ErrorLevel, format, args...)
+}
+
+func Panicf(format string, args ...any) {
+	Default().Panicf(format, args...)
+}
+
+// log formats and writes a single line if the given Level is enabled.
+func (l *Logger) log(level Level, format string, args ...any) {
+	out.mu.Lock()
+	defer out.mu.Unlock()
+	if level < out.level {
+		return
+	}
+	fields := append([]field{
+		{"time", time.Now().UTC().Format(time.RFC3339Nano)},
+		{"level", level.String()},
+		{"msg", fmt.Sprintf(format, args...)},
+	}, l.fields...)
+	var line string
+	if out.format == JsonFormat {
+		line = formatJson(fields)
+	} else {
+		line = formatLogfmt(fields)
+	}
+	_, _ = io.WriteString(out.w, line+"\n")
+}
+
+func formatJson(fields []field) string {
+	parts := make([]string, len(fields))
+	for i, f := range fields {
+		k, _ := json.Marshal(f.key)
+		v, err := json.Marshal(jsonValue(f.value))
+		if err != nil {
+			v, _ = json.Marshal(fmt.Sprint(f.value))
+		}
+		parts[i] = string(k) + ":" + string(v)
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// hexer is implemented by IDs like primitive.ObjectID, whose String function wraps their hexadecimal representation.
+type hexer interface {
+	Hex() string
+}
+
+// jsonValue converts values that do not marshal to something readable, like errors, to strings.
+func jsonValue(v any) any {
+	switch t := v.(type) {
+	case json.Marshaler:
+		return t
+	case hexer:
+		return t.Hex()
+	case error:
+		return t.Error()
+	case fmt.Stringer:
+		return t.String()
+	default:
+		return v
+	}
+}
+
+func formatLogfmt(fields []field) string {
+	parts := make([]string, len(fields))
+	for i, f := range fields {
+		v := "null"
+		if h, ok := f.value.(hexer); ok {
+			v = h.Hex()
+		} else if f.value != nil {
+			v = fmt.Sprint(f.value)
+		}
+		if v == "" || strings.ContainsAny(v, " =\"\t\n") {
+			v = fmt.Sprintf("%q", v)
+		}
+		parts[i] = f.key + "=" + v
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,42 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"regexp"
+	"testing"
+)
+
+func TestLogger_Formats(t *testing.T) {
+	buf := new(bytes.Buffer)
+	SetOutput(buf)
+	defer SetOutput(os.Stderr)
+	l := With("config_id", "burpee", "scraper_id", "burpee_html").With("url", "https://www.burpee.com/?a=b")
+	cases := []struct {
+		format   string
+		level    Level
+		log      func()
+		expected string
+	}{
+		{LogfmtFormat, InfoLevel, func() { l.Infof("Crawled %d pages", 2) },
+			`^time=\S+ level=info msg="Crawled 2 pages" config_id=burpee scraper_id=burpee_html url="https://www.burpee.com/\?a=b"\n$`},
+		{JsonFormat, InfoLevel, func() { l.With("error", errors.New("timeout")).Warnf("Failed") },
+			`^\{"time":"\S+","level":"warn","msg":"Failed","config_id":"burpee","scraper_id":"burpee_html","url":"https://www.burpee.com/\?a=b","error":"timeout"\}\n$`},
+		{JsonFormat, WarnLevel, func() { l.Infof("Discarded") }, `^$`},
+		{LogfmtFormat, DebugLevel, func() { Default().With("odd").Debugf("") }, `^time=\S+ level=debug msg="" !BADKEY=odd\n$`},
+	}
+	for _, c := range cases {
+		buf.Reset()
+		SetLevel(c.level)
+		if err := SetFormat(c.format); err != nil {
+			t.Fatal(err)
+		}
+		c.log()
+		if !regexp.MustCompile(c.expected).MatchString(buf.String()) {
+			t.Errorf("Expected line matching %s, got %q", c.expected, buf.String())
+		}
+	}
+	SetLevel(InfoLevel)
+	_ = SetFormat(LogfmtFormat)
+}
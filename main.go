@@ -1,25 +1,11 @@
 package main
 
 import (
-	"github.com/mmaaskant/gro-crop-scraper/config"
-	"github.com/mmaaskant/gro-crop-scraper/database"
-	"github.com/mmaaskant/gro-crop-scraper/scraper"
-	"log"
+	"github.com/mmaaskant/gro-crop-scraper/cli"
+	"os"
 )
 
-// main gets all configs and their steps and filters them based on the given flags.
-// Config filters are provided as command flags in the format" "--<config_name>",
-// Step filters are provided as command flags in the format: "--<step_name>".
-// If no filters are provided, all configs and their steps will be executed.
+// main runs the command given by the arguments, see cli.Cli, or all steps of all configs if no command is given.
 func main() {
-	db, err := database.NewDb(database.NewMongoDbDriver())
-	if err != nil {
-		log.Panicf("Failed to connect to database, error: %s", err)
-	}
-	sm := scraper.NewManager(db)
-	configs := config.GetConfigs()
-	for _, c := range configs {
-		sm.RegisterScrapers(c.Scrapers)
-	}
-	sm.Start()
+	os.Exit(cli.NewCli(os.Stdout, os.Stderr).Run(os.Args[1:]))
 }
@@ -0,0 +1,129 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Registry holds all collectors exposed by Server, it is separate from the default registry
+// so only metrics registered by the scraper are exposed.
+var Registry = prometheus.NewRegistry()
+
+var (
+	// Requests counts the HTTP requests made by crawlers by scraper, host and status code,
+	// requests that failed before a response was received have the status "error".
+	Requests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "gro_crawler_requests_total",
+		Help: "HTTP requests made by crawlers by scraper, host and status code.",
+	}, []string{"scraper", "host", "status"})
+	// FetchDuration observes how long it takes crawlers to receive a response and read its body.
+	FetchDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "gro_crawler_fetch_duration_seconds",
+		Help:    "Time spent fetching a single page by scraper and host.",
+		Buckets: prometheus.ExponentialBuckets(0.05, 2, 10),
+	}, []string{"scraper", "host"})
+	// QueueDepth holds the amount of jobs published to a step's workers that have not been processed yet.
+	QueueDepth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gro_queue_depth",
+		Help: "Jobs waiting to be processed by step.",
+	}, []string{"step"})
+	// PagesExtracted counts the crawled pages that were stored to be filtered.
+	PagesExtracted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "gro_crawler_pages_extracted_total",
+		Help: "Crawled pages stored to be filtered by scraper.",
+	}, []string{"scraper"})
+	// FilterResults counts filtered documents by scraper and result, which is either "hit", "miss" or "error".
+	FilterResults = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "gro_filter_results_total",
+		Help: "Filtered documents by scraper and result, a hit is a document that data was extracted from.",
+	}, []string{"scraper", "result"})
+	// DbWriteDuration observes how long database writes take by operation and table.
+	DbWriteDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "gro_db_write_duration_seconds",
+		Help:    "Time spent writing to the database by operation and table.",
+		Buckets: prometheus.ExponentialBuckets(0.001, 2, 12),
+	}, []string{"operation", "table"})
+	// ScheduleNextRun holds the time of the next scheduled run of a scraper's step as a Unix timestamp.
+	ScheduleNextRun = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gro_schedule_next_run_timestamp_seconds",
+		Help: "Next scheduled run by scraper and step.",
+	}, []string{"scraper", "step"})
+	// ScheduleLastRun holds the time of the last scheduled run of a scraper's step as a Unix timestamp.
+	ScheduleLastRun = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gro_schedule_last_run_timestamp_seconds",
+		Help: "Last scheduled run by scraper and step.",
+	}, []string{"scraper", "step"})
+	// ScheduledRuns counts scheduled runs by scraper, step and result, which is either "succeeded", "failed" or "skipped".
+	ScheduledRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "gro_scheduled_runs_total",
+		Help: "Scheduled runs by scraper, step and result.",
+	}, []string{"scraper", "step", "result"})
+	// ProxyRequests counts requests made through a proxy by proxy and result, which is either "ok" or "failed".
+	ProxyRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "gro_proxy_requests_total",
+		Help: "Requests made through a proxy by proxy and result.",
+	}, []string{"proxy", "result"})
+	// ProxyBenched is 1 while a proxy is benched for failing too often, and 0 otherwise.
+	ProxyBenched = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gro_proxy_benched",
+		Help: "Whether a proxy is benched for failing too often.",
+	}, []string{"proxy"})
+	// BlockedResponses counts responses classified as blocked, e.g. a captcha page, by scraper and host.
+	BlockedResponses = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "gro_crawler_blocked_total",
+		Help: "Responses classified as blocked by scraper and host.",
+	}, []string{"scraper", "host"})
+	// LostLocks counts locks that were lost while the step holding them was still running.
+	LostLocks = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "gro_scheduler_lost_locks_total",
+		Help: "Locks lost while their step was running by lock.",
+	}, []string{"lock"})
+	// DbWriteErrors counts failed database writes by operation and table.
+	DbWriteErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "gro_db_write_errors_total",
+		Help: "Failed database writes by operation and table.",
+	}, []string{"operation", "table"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		Requests,
+		FetchDuration,
+		QueueDepth,
+		PagesExtracted,
+		FilterResults,
+		DbWriteDuration,
+		DbWriteErrors,
+		ScheduleNextRun,
+		ScheduleLastRun,
+		ScheduledRuns,
+		LostLocks,
+		ProxyRequests,
+		ProxyBenched,
+		BlockedResponses,
+	)
+}
+
+// ObserveFetch records a request made by the crawler of the given scraper that started at the given time,
+// resp is nil if the request failed before a response was received.
+func ObserveFetch(scraperId string, req *http.Request, resp *http.Response, start time.Time) {
+	status := "error"
+	if resp != nil {
+		status = strconv.Itoa(resp.StatusCode)
+	}
+	Requests.WithLabelValues(scraperId, req.URL.Host, status).Inc()
+	FetchDuration.WithLabelValues(scraperId, req.URL.Host).Observe(time.Since(start).Seconds())
+}
+
+// ObserveDbWrite records a database write to the given table that started at the given time.
+func ObserveDbWrite(operation string, table string, start time.Time, err error) {
+	DbWriteDuration.WithLabelValues(operation, table).Observe(time.Since(start).Seconds())
+	if err != nil {
+		DbWriteErrors.WithLabelValues(operation, table).Inc()
+	}
+}
@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"github.com/mmaaskant/gro-crop-scraper/logger"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// Server exposes Registry on "/metrics" along with "/healthz", which reports the process is alive,
+// and "/readyz", which reports whether the scraper is connected to its database and running.
+type Server struct {
+	server *http.Server
+	ready  int32
+}
+
+func NewServer(addr string) *Server {
+	s := &Server{
+		&http.Server{Addr: addr, ReadHeaderTimeout: 10 * time.Second},
+		0,
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(Registry, promhttp.HandlerOpts{}))
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok"))
+	})
+	mux.HandleFunc("/readyz", s.handleReady)
+	s.server.Handler = mux
+	return s
+}
+
+// Start starts listening in the background, the scraper keeps running if the server fails.
+func (s *Server) Start() {
+	go func() {
+		if err := s.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.With("addr", s.server.Addr, "error", err).Errorf("Metrics server stopped")
+		}
+	}()
+	logger.With("addr", s.server.Addr).Infof("Serving metrics")
+}
+
+// Handle registers an additional handler, e.g. one exposing the schedule of a daemon.
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.server.Handler.(*http.ServeMux).Handle(pattern, handler)
+}
+
+// SetReady determines whether "/readyz" reports the scraper as ready.
+func (s *Server) SetReady(ready bool) {
+	var v int32
+	if ready {
+		v = 1
+	}
+	atomic.StoreInt32(&s.ready, v)
+}
+
+// Shutdown gracefully stops the server.
+func (s *Server) Shutdown() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.server.Shutdown(ctx); err != nil {
+		logger.With("addr", s.server.Addr, "error", err).Errorf("Failed to shut down metrics server")
+	}
+}
+
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	if atomic.LoadInt32(&s.ready) == 0 {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte("not ready"))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("ok"))
+}
@@ -0,0 +1,34 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServer_Endpoints(t *testing.T) {
+	s := NewServer(":0")
+	req := httptest.NewRequest(http.MethodGet, "https://www.burpee.com/vegetables", nil)
+	ObserveFetch("burpee_html", req, &http.Response{StatusCode: http.StatusOK}, time.Now())
+	cases := []struct {
+		path   string
+		ready  bool
+		status int
+		body   string
+	}{
+		{"/healthz", false, http.StatusOK, "ok"},
+		{"/readyz", false, http.StatusServiceUnavailable, "not ready"},
+		{"/readyz", true, http.StatusOK, "ok"},
+		{"/metrics", true, http.StatusOK, `gro_crawler_requests_total{host="www.burpee.com",scraper="burpee_html",status="200"} 1`},
+	}
+	for _, c := range cases {
+		s.SetReady(c.ready)
+		rec := httptest.NewRecorder()
+		s.server.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, c.path, nil))
+		if rec.Code != c.status || !strings.Contains(rec.Body.String(), c.body) {
+			t.Errorf("Expected %s to return %d containing %q, got %d: %s", c.path, c.status, c.body, rec.Code, rec.Body.String())
+		}
+	}
+}
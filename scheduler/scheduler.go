@@ -0,0 +1,275 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/mmaaskant/gro-crop-scraper/crawler"
+	"github.com/mmaaskant/gro-crop-scraper/database"
+	"github.com/mmaaskant/gro-crop-scraper/filter"
+	"github.com/mmaaskant/gro-crop-scraper/logger"
+	"github.com/mmaaskant/gro-crop-scraper/metrics"
+	"github.com/mmaaskant/gro-crop-scraper/scraper"
+	"github.com/robfig/cron/v3"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// LockTtl determines how long the lock held by a running job stays valid without being refreshed,
+// running jobs refresh their lock well before it expires.
+const LockTtl = 5 * time.Minute
+
+const (
+	SucceededResult = "succeeded"
+	FailedResult    = "failed"
+	SkippedResult   = "skipped"
+)
+
+// parser parses standard cron expressions, e.g. "0 3 * * 1", as well as descriptors like "@daily" and intervals
+// like "@every 6h". Expressions can be prefixed by a time zone, e.g. "CRON_TZ=Europe/Amsterdam 0 3 * * *".
+var parser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// ParseSpec validates the given cron expression or interval.
+func ParseSpec(spec string) error {
+	_, err := parser.Parse(spec)
+	return err
+}
+
+// Scheduler runs the steps of scraper.Scraper instances on their own schedule. Overlapping runs of the same scraper,
+// whether by this or another process or the CLI, are prevented by the lock named by LockName held in the "locks" table.
+type Scheduler struct {
+	db       *database.Db
+	cron     *cron.Cron
+	owner    string
+	refilter bool
+	jobs     []*job
+	mutex    sync.RWMutex
+}
+
+func NewScheduler(db *database.Db) *Scheduler {
+	return &Scheduler{
+		db,
+		cron.New(cron.WithParser(parser)),
+		LockOwner(),
+		false,
+		make([]*job, 0),
+		sync.RWMutex{},
+	}
+}
+
+// job runs a single step of a scraper.Scraper on its schedule.
+type job struct {
+	scheduler  *Scheduler
+	scraper    *scraper.Scraper
+	step       string
+	spec       string
+	entryId    cron.EntryID
+	lastRun    time.Time
+	lastResult string
+}
+
+// SetRefilter determines if scheduled filter steps also filter scraped data that was filtered by a previous version
+// of its filter.Filter, see scraper.Manager.SetRefilter.
+func (s *Scheduler) SetRefilter(enabled bool) {
+	s.refilter = enabled
+}
+
+// Schedule schedules the given step of the scraper.Scraper, which is either "crawl" or "filter",
+// using a cron expression or interval.
+func (s *Scheduler) Schedule(sc *scraper.Scraper, step string, spec string) error {
+	if step != crawler.StepId && step != filter.StepId {
+		return fmt.Errorf("unknown step %s, expected %s or %s", step, crawler.StepId, filter.StepId)
+	}
+	if step == crawler.StepId && sc.Crawler == nil || step == filter.StepId && sc.Filter == nil {
+		return fmt.Errorf("scraper %s does not have a component to run the %s step", sc.GetScraperId(), step)
+	}
+	j := &job{
+		scheduler: s,
+		scraper:   sc,
+		step:      step,
+		spec:      spec,
+	}
+	id, err := s.cron.AddJob(spec, j)
+	if err != nil {
+		return fmt.Errorf("invalid schedule %q for the %s step of scraper %s, error: %w", spec, step, sc.GetScraperId(), err)
+	}
+	j.entryId = id
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.jobs = append(s.jobs, j)
+	return nil
+}
+
+// Start starts running the scheduled jobs in the background.
+func (s *Scheduler) Start() {
+	s.cron.Start()
+	for _, st := range s.Status() {
+		logger.With("config_id", st.ConfigId, "scraper_id", st.ScraperId, "step", st.Step, "schedule", st.Schedule).
+			Infof("Scheduled next run at %s", st.NextRun.Format(time.RFC3339))
+	}
+	s.updateMetrics()
+}
+
+// Stop stops scheduling jobs and waits for running jobs to finish.
+func (s *Scheduler) Stop() {
+	<-s.cron.Stop().Done()
+}
+
+// JobStatus describes a scheduled job along with its last and next run.
+type JobStatus struct {
+	ConfigId   string     `json:"config_id"`
+	ScraperId  string     `json:"scraper_id"`
+	Step       string     `json:"step"`
+	Schedule   string     `json:"schedule"`
+	LastRun    *time.Time `json:"last_run"`
+	LastResult string     `json:"last_result"`
+	NextRun    time.Time  `json:"next_run"`
+}
+
+// Status returns the JobStatus of every scheduled job.
+func (s *Scheduler) Status() []*JobStatus {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	status := make([]*JobStatus, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		st := &JobStatus{
+			ConfigId:   j.scraper.GetConfigId(),
+			ScraperId:  j.scraper.GetScraperId(),
+			Step:       j.step,
+			Schedule:   j.spec,
+			LastResult: j.lastResult,
+			NextRun:    s.cron.Entry(j.entryId).Next,
+		}
+		if !j.lastRun.IsZero() {
+			lastRun := j.lastRun
+			st.LastRun = &lastRun
+		}
+		if st.NextRun.IsZero() {
+			if schedule, err := parser.Parse(j.spec); err == nil {
+				st.NextRun = schedule.Next(time.Now())
+			}
+		}
+		status = append(status, st)
+	}
+	return status
+}
+
+// ServeHTTP writes the Status of all jobs as JSON.
+func (s *Scheduler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(s.Status()); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// updateMetrics exposes the last and next run of every job.
+func (s *Scheduler) updateMetrics() {
+	for _, st := range s.Status() {
+		metrics.ScheduleNextRun.WithLabelValues(st.ScraperId, st.Step).Set(float64(st.NextRun.Unix()))
+		if st.LastRun != nil {
+			metrics.ScheduleLastRun.WithLabelValues(st.ScraperId, st.Step).Set(float64(st.LastRun.Unix()))
+		}
+	}
+}
+
+// Run implements cron.Job and runs the job's step unless the scraper is already running.
+func (j *job) Run() {
+	l := logger.With("config_id", j.scraper.GetConfigId(), "scraper_id", j.scraper.GetScraperId(), "step", j.step)
+	start := time.Now()
+	result := j.run(l)
+	j.scheduler.mutex.Lock()
+	j.lastRun = start
+	j.lastResult = result
+	j.scheduler.mutex.Unlock()
+	metrics.ScheduledRuns.WithLabelValues(j.scraper.GetScraperId(), j.step, result).Inc()
+	j.scheduler.updateMetrics()
+}
+
+// run runs the job's step while holding the scraper's lock, and returns the result of the run.
+func (j *job) run(l *logger.Logger) (result string) {
+	s := j.scheduler
+	lock := LockName(j.scraper)
+	acquired, err := s.db.AcquireLock(lock, s.owner, LockTtl)
+	if err != nil {
+		l.With("error", err).Errorf("Failed to acquire lock, skipping scheduled run")
+		return FailedResult
+	}
+	if !acquired {
+		l.Warnf("Scraper is already running, skipping scheduled run")
+		return SkippedResult
+	}
+	done := make(chan struct{})
+	lost := RefreshLock(s.db, l, lock, s.owner, done)
+	defer func() {
+		close(done)
+		if err := s.db.ReleaseLock(lock, s.owner); err != nil {
+			l.With("error", err).Errorf("Failed to release lock")
+		}
+		if r := recover(); r != nil {
+			l.Errorf("Scheduled run panicked: %v", r)
+			result = FailedResult
+		}
+	}()
+	var sc *scraper.Scraper
+	if j.step == crawler.StepId {
+		sc = scraper.NewScraper(j.scraper.Crawler, j.scraper.Calls, nil)
+	} else {
+		sc = scraper.NewScraper(nil, nil, j.scraper.Filter)
+	}
+	sc.SetConcurrency(j.scraper.Concurrency)
+	sc.SetTag(j.scraper.Tag)
+	sm := scraper.NewManager(s.db.Clone())
+	sm.SetRefilter(s.refilter)
+	// Record the run as if the step was run using the CLI, e.g. "crawl burpee/burpee_html".
+	sm.SetFlags([]string{j.step, j.scraper.GetConfigId() + "/" + j.scraper.GetScraperId()})
+	sm.RegisterScraper(sc)
+	sm.Start()
+	select {
+	case <-lost:
+		l.Warnf("Scheduled run finished after its lock was lost")
+		return FailedResult
+	default:
+		return SucceededResult
+	}
+}
+
+// LockOwner returns the owner of the locks held by this process, which is its hostname and process ID.
+func LockOwner() string {
+	hostname, _ := os.Hostname()
+	return fmt.Sprintf("%s:%d", hostname, os.Getpid())
+}
+
+// LockName returns the name of the lock held while a step of the given scraper.Scraper is running.
+func LockName(sc *scraper.Scraper) string {
+	return fmt.Sprintf("scraper:%s/%s", sc.GetConfigId(), sc.GetScraperId())
+}
+
+// RefreshLock refreshes the given lock held by the given owner in the background until done is closed.
+// The returned channel is closed once the lock is lost, in which case it is no longer refreshed
+// and the step holding it should be aborted as another process may run it as well.
+func RefreshLock(db *database.Db, l *logger.Logger, lock string, owner string, done chan struct{}) <-chan struct{} {
+	lost := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(LockTtl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				isLost, err := db.RefreshLock(lock, owner, LockTtl)
+				if err != nil {
+					l.With("error", err).Errorf("Failed to refresh lock")
+				}
+				if isLost {
+					l.Errorf("Lock was lost, it is no longer held by this process")
+					metrics.LostLocks.WithLabelValues(lock).Inc()
+					close(lost)
+					return
+				}
+			}
+		}
+	}()
+	return lost
+}
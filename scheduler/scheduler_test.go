@@ -0,0 +1,47 @@
+package scheduler
+
+import (
+	"github.com/mmaaskant/gro-crop-scraper/attribute"
+	"github.com/mmaaskant/gro-crop-scraper/crawler"
+	"github.com/mmaaskant/gro-crop-scraper/filter"
+	"github.com/mmaaskant/gro-crop-scraper/scraper"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestScheduler_Schedule(t *testing.T) {
+	s := NewScheduler(nil)
+	sc := scraper.NewScraper(crawler.NewHtmlCrawler(&http.Client{}), []*crawler.Call{
+		crawler.NewCall(crawler.NewRequest(http.MethodGet, "https://www.burpee.com", nil), crawler.DiscoverRequestType),
+	}, nil)
+	sc.SetTag(attribute.NewTag("burpee", "burpee_html"))
+	if err := s.Schedule(sc, crawler.StepId, "@every 6h"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Schedule(sc, crawler.StepId, "0 3 * * 1"); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		step string
+		spec string
+	}{
+		{filter.StepId, "@every 6h"},
+		{"map", "@every 6h"},
+		{crawler.StepId, "every monday"},
+	} {
+		if err := s.Schedule(sc, c.step, c.spec); err == nil {
+			t.Errorf("Expected scheduling step %s using %q to fail", c.step, c.spec)
+		}
+	}
+	status := s.Status()
+	if len(status) != 2 {
+		t.Fatalf("Expected 2 scheduled jobs, got %d", len(status))
+	}
+	if next := time.Until(status[0].NextRun); next < 6*time.Hour-time.Minute || next > 6*time.Hour {
+		t.Errorf("Expected next run in 6h, got %s", next)
+	}
+	if next := status[1].NextRun; next.Weekday() != time.Monday || next.Hour() != 3 || status[1].LastRun != nil {
+		t.Errorf("Expected next run on monday at 3 and no last run, got %s and %v", next, status[1].LastRun)
+	}
+}
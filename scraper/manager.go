@@ -5,23 +5,45 @@ import (
 	"github.com/mmaaskant/gro-crop-scraper/crawler"
 	"github.com/mmaaskant/gro-crop-scraper/database"
 	"github.com/mmaaskant/gro-crop-scraper/filter"
-	"log"
+	"github.com/mmaaskant/gro-crop-scraper/logger"
 	"os"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
+const (
+	// CrawlerWorkerCountEnv holds the default amount of workers of the crawl step.
+	CrawlerWorkerCountEnv = "GOPHERVISOR_CRAWLER_WORKER_COUNT"
+	// FilterWorkerCountEnv holds the default amount of workers of the filter step.
+	FilterWorkerCountEnv = "GOPHERVISOR_FILTER_WORKER_COUNT"
+)
+
+// defaultWorkerCounts holds the worker count of each step used if its env variable is not set.
+var defaultWorkerCounts = map[string]int{
+	CrawlerWorkerCountEnv: 10,
+	FilterWorkerCountEnv:  3,
+}
+
 // Manager oversees all registered Scraper instances and its components.
 type Manager struct {
+	db             *database.Db
 	crawlerManager *crawler.Manager
 	filterManager  *filter.Manager
 	scrapers       []*Scraper
+	refilter       bool
+	flags          []string
 }
 
 func NewManager(db *database.Db) *Manager {
 	return &Manager{
+		db,
 		crawler.NewManager(db),
 		filter.NewManager(db),
 		make([]*Scraper, 0),
+		false,
+		make([]string, 0),
 	}
 }
 
@@ -34,26 +56,144 @@ func (m *Manager) RegisterScrapers(scrapers []*Scraper) {
 func (m *Manager) RegisterScraper(s *Scraper) {
 	if s.Crawler != nil && s.Calls != nil {
 		m.crawlerManager.RegisterCrawler(s.Crawler, s.Calls)
+		if s.Concurrency != nil {
+			m.crawlerManager.SetConcurrency(s.Crawler, s.Concurrency.CrawlWorkers, s.Concurrency.HostWorkers)
+		}
 	}
 	if s.Filter != nil {
 		m.filterManager.RegisterFilter(s.Filter)
+		if s.Concurrency != nil {
+			m.filterManager.SetWorkerCount(s.Filter, s.Concurrency.FilterWorkers)
+		}
 	}
 	m.scrapers = append(m.scrapers, s)
 }
 
+// SetRefilter determines if the filter step also filters scraped data again that was filtered by a previous
+// version of its filter.Filter, this allows changed filter criteria to be applied without crawling again.
+func (m *Manager) SetRefilter(enabled bool) {
+	m.refilter = enabled
+}
+
+// SetFlags sets the flags the scraper was invoked with, these are stored along with the run.
+func (m *Manager) SetFlags(flags []string) {
+	m.flags = flags
+}
+
 // Start starts Scraper and its components and waits till all components have finished running.
+// Every invocation is tracked as a run in the "runs" table, which holds the counters of each scraper and step.
 func (m *Manager) Start() {
-	m.crawlerManager.Start(m.getWorkerCount("GOPHERVISOR_CRAWLER_WORKER_COUNT"))
-	m.filterManager.Start(m.getWorkerCount("GOPHERVISOR_FILTER_WORKER_COUNT"))
+	run, err := m.db.StartRun(m.flags, m.getConfigIds())
+	if err != nil {
+		logger.Panicf("Failed to start run, error: %s", err)
+	}
+	l := logger.With("run_id", run.Id())
+	l.With("flags", m.flags, "configs", m.getConfigIds()).Infof("Started run")
+	done := make(chan struct{})
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		m.flushRun(l, run, done)
+	}()
+	defer func() {
+		close(done)
+		wg.Wait()
+		r := recover()
+		var runErr error
+		if r != nil {
+			runErr = fmt.Errorf("run panicked: %v", r)
+		}
+		if err := m.db.FinishRun(run, runErr); err != nil {
+			l.With("error", err).Errorf("Failed to finish run")
+		}
+		if r != nil {
+			panic(r)
+		}
+		l.With("counters", run.Counters()).Infof("Finished run")
+	}()
+	m.filterManager.SetProvenance(m.getBool("FILTER_PROVENANCE"))
+	m.filterManager.SetRefilter(m.refilter)
+	m.filterManager.SetRetention(m.getDuration("SCRAPED_DATA_RETENTION"))
+	m.crawlerManager.Start(WorkerCount(CrawlerWorkerCountEnv))
+	if err := m.db.FlushRun(run); err != nil {
+		l.With("error", err).Errorf("Failed to store run counters")
+	}
+	m.filterManager.Start(WorkerCount(FilterWorkerCountEnv))
 }
 
-// getWorkerCount gets a worker count from an env variable and attempts to convert it to an int.
-func (m *Manager) getWorkerCount(env string) int {
-	workerCount, err := strconv.Atoi(os.Getenv(env))
-	if err != nil {
-		log.Panicf("Could not convert env variable %s with value %v to int",
+// flushRun stores the counters of the given run every database.RunFlushInterval until done is closed.
+func (m *Manager) flushRun(l *logger.Logger, run *database.Run, done chan struct{}) {
+	ticker := time.NewTicker(database.RunFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if err := m.db.FlushRun(run); err != nil {
+				l.With("error", err).Errorf("Failed to store run counters")
+			}
+		}
+	}
+}
+
+// getConfigIds returns the IDs of the configs of all registered Scraper instances.
+func (m *Manager) getConfigIds() []string {
+	configIds := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, s := range m.scrapers {
+		if s.Tag != nil && !seen[s.GetConfigId()] {
+			seen[s.GetConfigId()] = true
+			configIds = append(configIds, s.GetConfigId())
+		}
+	}
+	return configIds
+}
+
+// WorkerCount gets the default worker count of each Scraper from an env variable and attempts to convert it to an int,
+// Scraper instances with their own Concurrency override it. If the env variable is not set the step's default
+// worker count is used, or a single worker for an unknown env variable.
+func WorkerCount(env string) int {
+	if strings.TrimSpace(os.Getenv(env)) == "" {
+		if workerCount, ok := defaultWorkerCounts[env]; ok {
+			return workerCount
+		}
+		return 1
+	}
+	workerCount, err := strconv.Atoi(strings.TrimSpace(os.Getenv(env)))
+	if err != nil || workerCount < 1 {
+		logger.Panicf("Could not convert env variable %s with value %v to a positive int",
 			fmt.Sprintf("${%s}", env), os.Getenv(env),
 		)
 	}
 	return workerCount
 }
+
+// getDuration gets an optional time.Duration from an env variable, an empty env variable is considered 0.
+func (m *Manager) getDuration(env string) time.Duration {
+	if os.Getenv(env) == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(os.Getenv(env))
+	if err != nil {
+		logger.Panicf("Could not convert env variable %s with value %v to duration",
+			fmt.Sprintf("${%s}", env), os.Getenv(env),
+		)
+	}
+	return d
+}
+
+// getBool gets an optional boolean from an env variable, an empty env variable is considered false.
+func (m *Manager) getBool(env string) bool {
+	if os.Getenv(env) == "" {
+		return false
+	}
+	b, err := strconv.ParseBool(os.Getenv(env))
+	if err != nil {
+		logger.Panicf("Could not convert env variable %s with value %v to bool",
+			fmt.Sprintf("${%s}", env), os.Getenv(env),
+		)
+	}
+	return b
+}
@@ -31,6 +31,10 @@ func TestNewScraperManager_Start(t *testing.T) {
 	if err != nil {
 		t.Errorf("Failed to tear down test data, error: %s", err)
 	}
+	err = db.DeleteMany(database.RunTableName, map[string]any{"configs": TestConfigId})
+	if err != nil {
+		t.Errorf("Failed to tear down test runs, error: %s", err)
+	}
 }
 
 func newTestHtmlCrawler(url string) *crawler.HtmlCrawler {
@@ -46,3 +50,14 @@ func getTestHtmlCrawlerCalls(url string) []*crawler.Call {
 		crawler.DiscoverRequestType,
 	)}
 }
+
+func TestWorkerCount(t *testing.T) {
+	t.Setenv(CrawlerWorkerCountEnv, "")
+	t.Setenv(FilterWorkerCountEnv, " 5 ")
+	if c := WorkerCount(CrawlerWorkerCountEnv); c != 10 {
+		t.Errorf("Expected the default of 10 crawl workers if %s is not set, got %d", CrawlerWorkerCountEnv, c)
+	}
+	if c := WorkerCount(FilterWorkerCountEnv); c != 5 {
+		t.Errorf("Expected 5 filter workers, got %d", c)
+	}
+}
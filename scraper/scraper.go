@@ -13,6 +13,8 @@ type Scraper struct {
 	Crawler crawler.Crawler
 	Calls   []*crawler.Call
 	Filter  filter.Filter
+	// Concurrency limits the amount of workers used by each step, nil uses the default amount of workers.
+	Concurrency *Concurrency
 	//Mapper mapper.Mapper
 	//Compiler compiler.Compiler
 }
@@ -23,9 +25,33 @@ func NewScraper(c crawler.Crawler, calls []*crawler.Call, f filter.Filter) *Scra
 		c,
 		calls,
 		f,
+		nil,
+	}
+}
+
+// Concurrency limits the amount of workers used by the steps of a single Scraper, which allows each supplier to be
+// scraped at its own pace. A limit of 0 uses the default set by the GOPHERVISOR_CRAWLER_WORKER_COUNT and
+// GOPHERVISOR_FILTER_WORKER_COUNT env variables, or 10 and 3 workers if these are not set.
+// HostWorkers limits the amount of calls crawled per host at the same time and does not limit them if 0.
+type Concurrency struct {
+	CrawlWorkers  int
+	HostWorkers   int
+	FilterWorkers int
+}
+
+func NewConcurrency(crawlWorkers int, hostWorkers int, filterWorkers int) *Concurrency {
+	return &Concurrency{
+		crawlWorkers,
+		hostWorkers,
+		filterWorkers,
 	}
 }
 
+// SetConcurrency sets the Concurrency of the Scraper's steps.
+func (s *Scraper) SetConcurrency(c *Concurrency) {
+	s.Concurrency = c
+}
+
 func (s *Scraper) SetTag(t *attribute.Tag) {
 	s.Tag = t
 	if s.Crawler != nil {
@@ -0,0 +1,289 @@
+package warc
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"github.com/mmaaskant/gro-crop-scraper/attribute"
+	"github.com/mmaaskant/gro-crop-scraper/crawler"
+	"github.com/mmaaskant/gro-crop-scraper/database"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Fields used to tag records with the config and scraper they were crawled by, so they can be imported again.
+const (
+	ConfigIdField  = "Gro-Config-Id"
+	ScraperIdField = "Gro-Scraper-Id"
+)
+
+// Export writes a warcinfo record followed by a request and response record for every document
+// in the "scraped_data" table matching the given params, and returns the amount of documents exported.
+func Export(db *database.Db, w *Writer, params map[string]any) (int, error) {
+	info := []string{"software: gro-crop-scraper", "format: WARC File Format 1.1"}
+	for _, k := range sortedKeys(params) {
+		info = append(info, fmt.Sprintf("%s: %v", k, params[k]))
+	}
+	warcinfo := NewRecord(WarcinfoType, time.Now(), []byte(strings.Join(info, "\r\n")+"\r\n"))
+	warcinfo.Set("Content-Type", "application/warc-fields")
+	if err := w.WriteRecord(warcinfo); err != nil {
+		return 0, err
+	}
+	iterator, err := db.GetMany(database.ScrapedDataTableName, params)
+	if err != nil {
+		return 0, err
+	}
+	exported := 0
+	for e, err := iterator.Next(); e != nil || err != nil; e, err = iterator.Next() {
+		if err != nil {
+			return exported, err
+		}
+		request, response, err := newRecords(e)
+		if err != nil {
+			return exported, fmt.Errorf("failed to export document %v, error: %w", e.Id, err)
+		}
+		if err = w.WriteRecord(request); err != nil {
+			return exported, err
+		}
+		if err = w.WriteRecord(response); err != nil {
+			return exported, err
+		}
+		exported++
+	}
+	return exported, nil
+}
+
+// Import stores every response record read from the given Reader in the "scraped_data" table so it can be filtered,
+// and returns the amount of responses imported. Records are tagged using their Gro-Config-Id and Gro-Scraper-Id fields,
+// unless a Tag is provided which is used for all records instead.
+func Import(db *database.Db, r *Reader, tag *attribute.Tag) (int, error) {
+	requests := make(map[string]*http.Request)
+	imported := 0
+	for record, err := r.Next(); err != io.EOF; record, err = r.Next() {
+		if err != nil {
+			return imported, err
+		}
+		switch record.Type() {
+		case RequestType:
+			req, err := parseRequest(record)
+			if err != nil {
+				return imported, err
+			}
+			if id := record.Get("WARC-Concurrent-To"); id != "" {
+				requests[id] = req
+			}
+		case ResponseType:
+			cd, crawledAt, err := parseResponse(record, requests[record.Get("WARC-Record-ID")], tag)
+			if err != nil {
+				return imported, err
+			}
+			delete(requests, record.Get("WARC-Record-ID"))
+			if err = crawler.StoreData(db, cd, crawledAt); err != nil {
+				return imported, err
+			}
+			imported++
+		}
+	}
+	return imported, nil
+}
+
+// newRecords returns a request and response record for the given "scraped_data" database.Entity,
+// documents that were crawled before their metadata was stored are exported as a GET request and a 200 response.
+func newRecords(e *database.Entity) (*Record, *Record, error) {
+	url := fmt.Sprint(e.Data["url"])
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	crawledAt := time.Now()
+	for _, k := range []string{"crawled_at", "created_at"} {
+		if t, ok := toTime(e.Data[k]); ok {
+			crawledAt = t
+			break
+		}
+	}
+	body := fmt.Sprint(e.Data["data"])
+
+	statusCode, proto, responseHeader := http.StatusOK, "HTTP/1.1", http.Header{}
+	if response, ok := toMap(e.Data["response"]); ok {
+		if code, ok := toInt(response["status_code"]); ok {
+			statusCode = code
+		}
+		if p, ok := response["proto"].(string); ok && p != "" {
+			proto = p
+		}
+		responseHeader = toHeader(response["header"])
+	}
+	// The stored body has already been decoded, so any encoding headers no longer apply.
+	for _, k := range []string{"Content-Encoding", "Transfer-Encoding", "Content-Length"} {
+		responseHeader.Del(k)
+	}
+	responseHeader.Set("Content-Length", fmt.Sprint(len(body)))
+	responseBlock := new(bytes.Buffer)
+	fmt.Fprintf(responseBlock, "%s %d %s\r\n", proto, statusCode, http.StatusText(statusCode))
+	writeHeader(responseBlock, responseHeader)
+	responseBlock.WriteString(body)
+
+	if request, ok := toMap(e.Data["request"]); ok {
+		if method, ok := request["method"].(string); ok && method != "" {
+			req.Method = method
+		}
+		req.Header = toHeader(request["header"])
+	}
+	requestBlock := new(bytes.Buffer)
+	fmt.Fprintf(requestBlock, "%s %s HTTP/1.1\r\nHost: %s\r\n", req.Method, req.URL.RequestURI(), req.URL.Host)
+	writeHeader(requestBlock, req.Header)
+
+	response := NewRecord(ResponseType, crawledAt, responseBlock.Bytes())
+	response.Set("WARC-Target-URI", url)
+	response.Set("Content-Type", "application/http;msgtype=response")
+	response.Set(ConfigIdField, fmt.Sprint(e.Data["config_id"]))
+	response.Set(ScraperIdField, fmt.Sprint(e.Data["scraper_id"]))
+	request := NewRecord(RequestType, crawledAt, requestBlock.Bytes())
+	request.Set("WARC-Target-URI", url)
+	request.Set("WARC-Concurrent-To", response.Get("WARC-Record-ID"))
+	request.Set("Content-Type", "application/http;msgtype=request")
+	return request, response, nil
+}
+
+// parseRequest parses the HTTP request held by the given request record.
+func parseRequest(r *Record) (*http.Request, error) {
+	req, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(r.Block)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse request record %s, error: %w", r.Get("WARC-Record-ID"), err)
+	}
+	target, err := http.NewRequest(req.Method, r.Get("WARC-Target-URI"), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Del("Host")
+	target.Header = req.Header
+	return target, nil
+}
+
+// parseResponse parses the HTTP response held by the given response record into crawler.Data,
+// if no request is provided a GET request for the record's target URI is assumed.
+// The body is decompressed and decoded to UTF-8 using crawler.ReadBody, like it is when crawled.
+func parseResponse(r *Record, req *http.Request, tag *attribute.Tag) (*crawler.Data, time.Time, error) {
+	id := r.Get("WARC-Record-ID")
+	if tag == nil {
+		if r.Get(ConfigIdField) == "" || r.Get(ScraperIdField) == "" {
+			return nil, time.Time{}, fmt.Errorf("response record %s is not tagged with a config and scraper ID, provide them to import it", id)
+		}
+		tag = attribute.NewTag(r.Get(ConfigIdField), r.Get(ScraperIdField))
+	}
+	if req == nil {
+		var err error
+		if req, err = http.NewRequest(http.MethodGet, r.Get("WARC-Target-URI"), nil); err != nil {
+			return nil, time.Time{}, fmt.Errorf("invalid target URI for response record %s, error: %w", id, err)
+		}
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(r.Block)), req)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to parse response record %s, error: %w", id, err)
+	}
+	defer resp.Body.Close()
+	body, err := crawler.ReadBody(resp, 0)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to read response record %s, error: %w", id, err)
+	}
+	crawledAt, err := time.Parse(time.RFC3339, r.Get("WARC-Date"))
+	if err != nil {
+		crawledAt = time.Now()
+	}
+	return crawler.NewData(tag, crawler.NewCall(req, crawler.ExtractRequestType), resp, body, nil, nil), crawledAt, nil
+}
+
+// writeHeader writes the given http.Header in alphabetical order followed by an empty line.
+func writeHeader(w io.Writer, h http.Header) {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, v := range h[k] {
+			fmt.Fprintf(w, "%s: %s\r\n", k, v)
+		}
+	}
+	fmt.Fprint(w, "\r\n")
+}
+
+// toHeader converts a header stored in the database back to an http.Header.
+func toHeader(v any) http.Header {
+	h := http.Header{}
+	m, ok := toMap(v)
+	if !ok {
+		return h
+	}
+	for k, values := range m {
+		switch vs := values.(type) {
+		case []string:
+			for _, s := range vs {
+				h.Add(k, s)
+			}
+		case primitive.A:
+			for _, s := range vs {
+				h.Add(k, fmt.Sprint(s))
+			}
+		case []any:
+			for _, s := range vs {
+				h.Add(k, fmt.Sprint(s))
+			}
+		}
+	}
+	return h
+}
+
+func toMap(v any) (map[string]any, bool) {
+	if m, ok := database.AsMap(v); ok {
+		return m, true
+	}
+	switch m := v.(type) {
+	case map[string][]string:
+		converted := make(map[string]any, len(m))
+		for k, values := range m {
+			converted[k] = values
+		}
+		return converted, true
+	default:
+		return nil, false
+	}
+}
+
+func toInt(v any) (int, bool) {
+	switch i := v.(type) {
+	case int:
+		return i, true
+	case int32:
+		return int(i), true
+	case int64:
+		return int(i), true
+	default:
+		return 0, false
+	}
+}
+
+func toTime(v any) (time.Time, bool) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, true
+	case primitive.DateTime:
+		return t.Time(), true
+	default:
+		return time.Time{}, false
+	}
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,194 @@
+package warc
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const Version = "WARC/1.1"
+
+const (
+	WarcinfoType = "warcinfo"
+	RequestType  = "request"
+	ResponseType = "response"
+)
+
+// Field is a single named field within a Record's header, Record keeps its fields in the order they were added.
+type Field struct {
+	Name  string
+	Value string
+}
+
+// Record is a single WARC record, its Content-Length field is set based on Block once it is written.
+type Record struct {
+	Fields []Field
+	Block  []byte
+}
+
+// NewRecord returns a new Record of the given type with a new record ID and the given date.
+func NewRecord(recordType string, date time.Time, block []byte) *Record {
+	r := &Record{
+		make([]Field, 0),
+		block,
+	}
+	r.Set("WARC-Type", recordType)
+	r.Set("WARC-Record-ID", NewRecordId())
+	r.Set("WARC-Date", date.UTC().Format(time.RFC3339))
+	return r
+}
+
+// Get returns the value of the first field matching the given name, field names are case-insensitive.
+func (r *Record) Get(name string) string {
+	for _, f := range r.Fields {
+		if strings.EqualFold(f.Name, name) {
+			return f.Value
+		}
+	}
+	return ""
+}
+
+// Set replaces the value of the field matching the given name, or adds it if it does not exist yet.
+func (r *Record) Set(name string, value string) {
+	for i, f := range r.Fields {
+		if strings.EqualFold(f.Name, name) {
+			r.Fields[i].Value = value
+			return
+		}
+	}
+	r.Fields = append(r.Fields, Field{name, value})
+}
+
+// Type returns the record's WARC-Type.
+func (r *Record) Type() string {
+	return r.Get("WARC-Type")
+}
+
+// NewRecordId returns a new random record ID in the format "<urn:uuid:...>".
+func NewRecordId() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("<urn:uuid:%x-%x-%x-%x-%x>", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// Writer writes Record instances to an io.Writer, if compression is enabled every record is written
+// as a separate gzip member as is customary for ".warc.gz" files.
+type Writer struct {
+	w        io.Writer
+	compress bool
+}
+
+func NewWriter(w io.Writer, compress bool) *Writer {
+	return &Writer{
+		w,
+		compress,
+	}
+}
+
+// WriteRecord writes the given Record.
+func (ww *Writer) WriteRecord(r *Record) error {
+	buf := new(bytes.Buffer)
+	buf.WriteString(Version + "\r\n")
+	for _, f := range r.Fields {
+		if !strings.EqualFold(f.Name, "Content-Length") {
+			fmt.Fprintf(buf, "%s: %s\r\n", f.Name, f.Value)
+		}
+	}
+	fmt.Fprintf(buf, "Content-Length: %d\r\n\r\n", len(r.Block))
+	buf.Write(r.Block)
+	buf.WriteString("\r\n\r\n")
+	if !ww.compress {
+		_, err := ww.w.Write(buf.Bytes())
+		return err
+	}
+	gw := gzip.NewWriter(ww.w)
+	if _, err := gw.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+// Reader reads Record instances from an io.Reader, gzip compressed input is detected and decompressed.
+type Reader struct {
+	r *bufio.Reader
+}
+
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(2)
+	if err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		br = bufio.NewReader(gr)
+	}
+	return &Reader{
+		br,
+	}, nil
+}
+
+// Next reads the next Record, io.EOF is returned once all records have been read.
+func (wr *Reader) Next() (*Record, error) {
+	line, err := wr.readLine()
+	for err == nil && line == "" {
+		line, err = wr.readLine()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "WARC/") {
+		return nil, fmt.Errorf("expected WARC version line, got: %q", line)
+	}
+	r := &Record{
+		make([]Field, 0),
+		nil,
+	}
+	for {
+		line, err = wr.readLine()
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		if line == "" {
+			break
+		}
+		name, value, found := strings.Cut(line, ":")
+		if !found {
+			return nil, fmt.Errorf("invalid WARC header line: %q", line)
+		}
+		r.Fields = append(r.Fields, Field{strings.TrimSpace(name), strings.TrimSpace(value)})
+	}
+	length, err := strconv.Atoi(r.Get("Content-Length"))
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("invalid Content-Length %q for record %s", r.Get("Content-Length"), r.Get("WARC-Record-ID"))
+	}
+	r.Block = make([]byte, length)
+	if _, err = io.ReadFull(wr.r, r.Block); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	return r, nil
+}
+
+// readLine reads a single line without its line ending.
+func (wr *Reader) readLine() (string, error) {
+	line, err := wr.r.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,103 @@
+package warc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"github.com/mmaaskant/gro-crop-scraper/attribute"
+	"github.com/mmaaskant/gro-crop-scraper/database"
+	"io"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestWarc_RoundTrip(t *testing.T) {
+	crawledAt := time.Date(2022, 10, 4, 12, 30, 0, 0, time.UTC)
+	e := database.NewEntity(database.ScrapedDataTableName, map[string]any{
+		"config_id":  "burpee",
+		"scraper_id": "burpee_html",
+		"url":        "https://www.burpee.com/vegetables/tomatoes?page=2",
+		"data":       "<html><body>Tomato</body></html>",
+		"crawled_at": crawledAt,
+		"request": map[string]any{
+			"method": http.MethodGet,
+			"header": map[string][]string{"Accept": {"text/html"}},
+		},
+		"response": map[string]any{
+			"status_code": 200,
+			"proto":       "HTTP/1.1",
+			"header":      map[string][]string{"Content-Type": {"text/html"}, "Content-Encoding": {"gzip"}},
+		},
+	})
+	for _, compress := range []bool{false, true} {
+		request, response, err := newRecords(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf := new(bytes.Buffer)
+		w := NewWriter(buf, compress)
+		for _, r := range []*Record{request, response} {
+			if err = w.WriteRecord(r); err != nil {
+				t.Fatal(err)
+			}
+		}
+		r, err := NewReader(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		readRequest, err := r.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		readResponse, err := r.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = r.Next(); err != io.EOF {
+			t.Fatalf("compress %t: expected io.EOF after last record, got: %v", compress, err)
+		}
+		req, err := parseRequest(readRequest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if readRequest.Get("WARC-Concurrent-To") != readResponse.Get("WARC-Record-ID") {
+			t.Errorf("compress %t: request record is not linked to its response record", compress)
+		}
+		cd, date, err := parseResponse(readResponse, req, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cd.Data != e.Data["data"] || cd.Call.Request.URL.String() != e.Data["url"] {
+			t.Errorf("compress %t: expected body %q for %s, got %q for %s", compress, e.Data["data"], e.Data["url"], cd.Data, cd.Call.Request.URL)
+		}
+		if cd.GetConfigId() != "burpee" || cd.GetScraperId() != "burpee_html" {
+			t.Errorf("compress %t: expected tag burpee/burpee_html, got %s/%s", compress, cd.GetConfigId(), cd.GetScraperId())
+		}
+		if cd.Response.StatusCode != 200 || cd.Response.Header.Get("Content-Encoding") != "" || cd.Call.Request.Header.Get("Accept") != "text/html" {
+			t.Errorf("compress %t: unexpected metadata, response: %v, request header: %v", compress, cd.Response, cd.Call.Request.Header)
+		}
+		if !date.Equal(crawledAt) {
+			t.Errorf("compress %t: expected date %s, got %s", compress, crawledAt, date)
+		}
+	}
+}
+
+func TestParseResponse_DecodesBody(t *testing.T) {
+	// "Cœur de Bœuf" encoded as Windows-1252 and compressed using gzip, as it would be sent by a server.
+	buf := new(bytes.Buffer)
+	gw := gzip.NewWriter(buf)
+	_, _ = gw.Write([]byte("Tomato 'C\x9cur de B\x9cuf'"))
+	_ = gw.Close()
+	block := "HTTP/1.1 200 OK\r\nContent-Type: text/plain; charset=windows-1252\r\nContent-Encoding: gzip\r\n" +
+		"Content-Length: " + strconv.Itoa(buf.Len()) + "\r\n\r\n" + buf.String()
+	r := NewRecord("response", time.Now(), []byte(block))
+	r.Set("WARC-Target-URI", "https://www.example.com/tomato")
+	cd, _, err := parseResponse(r, nil, attribute.NewTag("example", "example_html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cd.Data != "Tomato 'Cœur de Bœuf'" || cd.Response.Header.Get("Content-Encoding") != "" {
+		t.Errorf("Expected the body to be decompressed and decoded, got %q with header %v", cd.Data, cd.Response.Header)
+	}
+}